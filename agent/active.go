@@ -0,0 +1,296 @@
+package agent
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wOvAN/zabbix"
+)
+
+// Item returned by server in active checks list.
+type ActiveCheck struct {
+	Key         string
+	Delay       time.Duration
+	LastLogSize int64
+	MTime       int64
+}
+
+type activeCheckJSON struct {
+	Key         string          `json:"key"`
+	Delay       json.RawMessage `json:"delay"`
+	LastLogSize int64           `json:"lastlogsize"`
+	MTime       int64           `json:"mtime"`
+}
+
+// Parses delay sent as number of seconds by old servers or as update interval string by new ones.
+// Custom intervals are ignored.
+func parseCheckDelay(raw json.RawMessage) (time.Duration, error) {
+	var seconds int64
+	if err := json.Unmarshal(raw, &seconds); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, err
+	}
+	delay, err := zabbix.ParseItemDelay(s)
+	if err != nil {
+		return 0, err
+	}
+	d, ok := delay.Update.Duration()
+	if !ok {
+		return 0, fmt.Errorf("Unresolved macro in delay %s", s)
+	}
+	return d, nil
+}
+
+// Value sent in agent data request. State 1 means not supported, Value is error message in this case.
+type AgentValue struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	State int    `json:"state,omitempty"`
+	Id    int64  `json:"id"`
+	Clock int64  `json:"clock"`
+	Ns    int    `json:"ns"`
+}
+
+// Client for active checks: gets list of active items for Host from server or proxy,
+// collects them with Registry handlers and sends values back in batches.
+// https://www.zabbix.com/documentation/4.0/manual/appendix/items/activepassive
+type ActiveClient struct {
+	Sender       *zabbix.Sender // connection settings for server or proxy
+	Host         string         // host name like Hostname in zabbix_agentd.conf
+	HostMetadata string         // used by active agent autoregistration
+	Registry     *Registry
+
+	RefreshInterval time.Duration // how often to refresh list of checks, 2m if zero
+	BufferSend      time.Duration // maximum age of buffered values, 5s by NewActiveClient
+	BufferSize      int           // buffered values sent at once, 100 if zero
+
+	ErrorLog *log.Logger // nil means log package logger
+
+	m       sync.Mutex
+	session string
+	lastId  int64
+}
+
+// Defaults like in zabbix_agentd.conf.
+const (
+	defaultRefreshInterval = 2 * time.Minute
+	defaultBufferSize      = 100
+	defaultTimeout         = 3 * time.Second
+	maxSendRetryDelay      = time.Minute
+)
+
+// Creates client for server address and host name. Sender times out after 3s, like zabbix_agentd.
+func NewActiveClient(serverAddr, host string, registry *Registry) *ActiveClient {
+	sender := zabbix.NewSender(serverAddr)
+	sender.Timeout = defaultTimeout
+	return &ActiveClient{
+		Sender:          sender,
+		Host:            host,
+		Registry:        registry,
+		RefreshInterval: defaultRefreshInterval,
+		BufferSend:      5 * time.Second,
+		BufferSize:      defaultBufferSize,
+	}
+}
+
+// Requests list of active checks for Host.
+func (c *ActiveClient) ActiveChecks() (checks []ActiveCheck, err error) {
+	request := map[string]string{"request": "active checks", "host": c.Host}
+	if c.HostMetadata != "" {
+		request["host_metadata"] = c.HostMetadata
+	}
+	var response struct {
+		Response string            `json:"response"`
+		Info     string            `json:"info"`
+		Data     []activeCheckJSON `json:"data"`
+	}
+	if err = c.Sender.Request(request, &response); err != nil {
+		return
+	}
+	if response.Response != "success" {
+		return nil, &zabbix.SenderError{Response: response.Response, Info: response.Info}
+	}
+
+	for _, d := range response.Data {
+		delay, err := parseCheckDelay(d.Delay)
+		if err != nil {
+			return nil, fmt.Errorf("Active check %s: %s", d.Key, err)
+		}
+		checks = append(checks, ActiveCheck{Key: d.Key, Delay: delay, LastLogSize: d.LastLogSize, MTime: d.MTime})
+	}
+	return
+}
+
+// Sends values in agent data request, filling empty Host, Id and Clock.
+// Values keep their ids, so server drops duplicates if values are sent again after error.
+func (c *ActiveClient) Send(values []AgentValue) (res *zabbix.SenderResponse, err error) {
+	c.m.Lock()
+	if c.session == "" {
+		c.session = newSession()
+	}
+	for i := range values {
+		if values[i].Id == 0 {
+			c.lastId++
+			values[i].Id = c.lastId
+		}
+		if values[i].Host == "" {
+			values[i].Host = c.Host
+		}
+	}
+	session := c.session
+	c.m.Unlock()
+
+	now := time.Now()
+	for i := range values {
+		if values[i].Clock == 0 {
+			values[i].Clock, values[i].Ns = now.Unix(), now.Nanosecond()
+		}
+	}
+	request := struct {
+		Request string       `json:"request"`
+		Session string       `json:"session"`
+		Data    []AgentValue `json:"data"`
+		Clock   int64        `json:"clock"`
+		Ns      int          `json:"ns"`
+	}{"agent data", session, values, now.Unix(), now.Nanosecond()}
+
+	res = &zabbix.SenderResponse{}
+	if err = c.Sender.Request(request, res); err != nil {
+		return nil, err
+	}
+	if res.Response != "success" {
+		return res, &zabbix.SenderError{Response: res.Response, Info: res.Info}
+	}
+	res.ParseInfo()
+	return
+}
+
+func newSession() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Returns id for next value.
+func (c *ActiveClient) nextId() int64 {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.lastId++
+	return c.lastId
+}
+
+// Collects value of check at time now. Id is assigned here, so value keeps it while buffered.
+func (c *ActiveClient) collect(key string, now time.Time) AgentValue {
+	v := AgentValue{Host: c.Host, Key: key, Id: c.nextId(), Clock: now.Unix(), Ns: now.Nanosecond()}
+	value, err := c.Registry.Get(key)
+	if err != nil {
+		v.State = 1
+		v.Value = err.Error()
+	} else {
+		v.Value = value
+	}
+	return v
+}
+
+// Refreshes checks, collects and sends values until stop is closed.
+// Buffered values are sent before returning. Errors are logged and retried, like zabbix_agentd does,
+// with delay doubled after each failed send up to 1m.
+// While server is unavailable at most 10*BufferSize values are kept, oldest ones are dropped.
+func (c *ActiveClient) Run(stop <-chan struct{}) {
+	refreshInterval, bufferSize := c.RefreshInterval, c.BufferSize
+	if refreshInterval <= 0 {
+		refreshInterval = defaultRefreshInterval
+	}
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	var (
+		checks      []ActiveCheck
+		nextCheck   = make(map[string]time.Time)
+		nextRefresh time.Time
+		buffer      []AgentValue
+		bufferStart time.Time
+		retryDelay  time.Duration
+		nextSend    time.Time
+	)
+
+	flush := func(now time.Time) {
+		if len(buffer) == 0 {
+			return
+		}
+		if _, err := c.Send(buffer); err != nil {
+			c.logf("Failed to send %d values: %s", len(buffer), err)
+			if max := bufferSize * 10; len(buffer) > max {
+				c.logf("Dropped %d oldest values", len(buffer)-max)
+				buffer = append([]AgentValue{}, buffer[len(buffer)-max:]...)
+			}
+			// keep values and try again later
+			if retryDelay *= 2; retryDelay == 0 {
+				retryDelay = time.Second
+			} else if retryDelay > maxSendRetryDelay {
+				retryDelay = maxSendRetryDelay
+			}
+			nextSend = now.Add(retryDelay)
+			return
+		}
+		buffer, retryDelay = nil, 0
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		if !now.Before(nextRefresh) {
+			newChecks, err := c.ActiveChecks()
+			if err != nil {
+				c.logf("Failed to get active checks for %s: %s", c.Host, err)
+			} else {
+				checks = newChecks
+			}
+			nextRefresh = now.Add(refreshInterval)
+		}
+
+		for _, check := range checks {
+			if check.Delay <= 0 || now.Before(nextCheck[check.Key]) {
+				continue
+			}
+			nextCheck[check.Key] = now.Add(check.Delay)
+			if len(buffer) == 0 {
+				bufferStart = now
+			}
+			buffer = append(buffer, c.collect(check.Key, now))
+		}
+
+		if (len(buffer) >= bufferSize || len(buffer) > 0 && now.Sub(bufferStart) >= c.BufferSend) &&
+			!now.Before(nextSend) {
+			flush(now)
+		}
+
+		select {
+		case <-stop:
+			flush(time.Now())
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *ActiveClient) logf(format string, args ...interface{}) {
+	if c.ErrorLog != nil {
+		c.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
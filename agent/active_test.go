@@ -0,0 +1,175 @@
+package agent_test
+
+import (
+	. "."
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wOvAN/zabbix"
+)
+
+// Local stand-in for server trapper port, handle returns response for each request.
+func serveActive(t *testing.T, handle func(request map[string]interface{}) interface{}) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			data, err := zabbix.ReadPacket(conn)
+			if err == nil {
+				var request map[string]interface{}
+				json.Unmarshal(data, &request)
+				data, _ = json.Marshal(handle(request))
+				zabbix.WritePacket(conn, data, false)
+			}
+			conn.Close()
+		}
+	}()
+	return l
+}
+
+func TestActiveClient(t *testing.T) {
+	agentData := make(chan []interface{}, 10)
+	l := serveActive(t, func(request map[string]interface{}) interface{} {
+		switch request["request"] {
+		case "active checks":
+			if request["host"] != "test host" || request["host_metadata"] != "linux" {
+				return zabbix.Params{"response": "failed", "info": "host not found"}
+			}
+			return zabbix.Params{"response": "success", "data": []zabbix.Params{
+				{"key": "agent.ping", "delay": 30, "lastlogsize": 0, "mtime": 0},
+				{"key": "app.missing", "delay": "1m;10s/1-5,09:00-18:00", "lastlogsize": 0, "mtime": 0},
+			}}
+		case "agent data":
+			data := request["data"].([]interface{})
+			agentData <- data
+			return zabbix.Params{"response": "success", "info": "processed: 1; failed: 1; total: 2; seconds spent: 0.1"}
+		}
+		return zabbix.Params{"response": "failed"}
+	})
+	defer l.Close()
+
+	c := NewActiveClient(l.Addr().String(), "test host", NewRegistry())
+	c.HostMetadata = "linux"
+	c.BufferSend = 0
+	if c.Sender.Timeout != 3*time.Second {
+		t.Errorf("Bad sender timeout %s", c.Sender.Timeout)
+	}
+
+	checks, err := c.ActiveChecks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 2 || checks[0].Delay != 30*time.Second || checks[1].Delay != time.Minute {
+		t.Fatalf("Bad checks %#v", checks)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.Run(stop)
+		close(done)
+	}()
+
+	select {
+	case data := <-agentData:
+		if len(data) != 2 {
+			t.Fatalf("Bad agent data %v", data)
+		}
+		ping := data[0].(map[string]interface{})
+		missing := data[1].(map[string]interface{})
+		if ping["value"] != "1" || ping["host"] != "test host" || ping["id"] != float64(1) {
+			t.Errorf("Bad value %v", ping)
+		}
+		if missing["state"] != float64(1) || missing["value"] != "Unsupported item key." {
+			t.Errorf("Bad not supported value %v", missing)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No agent data received")
+	}
+	close(stop)
+	<-done
+
+	c.Host = "other"
+	if _, err = c.ActiveChecks(); err == nil {
+		t.Error("Expected error for unknown host")
+	}
+}
+
+func TestActiveClientResend(t *testing.T) {
+	ids := make(chan []interface{}, 2)
+	failed := false
+	l := serveActive(t, func(request map[string]interface{}) interface{} {
+		var requestIds []interface{}
+		for _, v := range request["data"].([]interface{}) {
+			requestIds = append(requestIds, v.(map[string]interface{})["id"])
+		}
+		ids <- requestIds
+		if !failed {
+			failed = true
+			return zabbix.Params{"response": "failed", "info": "busy"}
+		}
+		return zabbix.Params{"response": "success", "info": "processed: 2; failed: 0; total: 2; seconds spent: 0.1"}
+	})
+	defer l.Close()
+
+	c := NewActiveClient(l.Addr().String(), "test host", NewRegistry())
+	values := []AgentValue{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}
+	if _, err := c.Send(values); err == nil {
+		t.Error("Expected error for failed response")
+	}
+	if _, err := c.Send(values); err != nil {
+		t.Fatal(err)
+	}
+	first, second := <-ids, <-ids
+	if !reflect.DeepEqual(first, []interface{}{float64(1), float64(2)}) || !reflect.DeepEqual(first, second) {
+		t.Errorf("Values are resent with other ids %v and %v", first, second)
+	}
+}
+
+func TestActiveClientRetry(t *testing.T) {
+	requests := make(chan string, 100)
+	l := serveActive(t, func(request map[string]interface{}) interface{} {
+		requests <- request["request"].(string)
+		if request["request"] == "active checks" {
+			return zabbix.Params{"response": "success", "data": []zabbix.Params{
+				{"key": "agent.ping", "delay": 1, "lastlogsize": 0, "mtime": 0},
+			}}
+		}
+		return zabbix.Params{"response": "failed", "info": "busy"}
+	})
+	defer l.Close()
+
+	// zero RefreshInterval and BufferSize mean defaults
+	c := &ActiveClient{Sender: zabbix.NewSender(l.Addr().String()), Host: "test host", Registry: NewRegistry(),
+		ErrorLog: log.New(ioutil.Discard, "", 0)}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.Run(stop)
+		close(done)
+	}()
+	time.Sleep(2500 * time.Millisecond)
+	close(stop)
+	<-done
+	close(requests)
+
+	counts := make(map[string]int)
+	for r := range requests {
+		counts[r]++
+	}
+	// without backoff values are sent on each tick and once more on stop
+	if counts["active checks"] != 1 || counts["agent data"] < 2 || counts["agent data"] > 3 {
+		t.Errorf("Bad requests %v", counts)
+	}
+}
@@ -0,0 +1,153 @@
+// Package agent implements Zabbix agent protocol, so Go programs can answer passive checks
+// and send active checks without zabbix_agentd.
+// https://www.zabbix.com/documentation/4.0/manual/appendix/items/activepassive
+package agent
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"sync"
+
+	"github.com/wOvAN/zabbix"
+)
+
+// Agent version returned by agent.version.
+var Version = "4.0.0"
+
+// Returns value for item key. Parameters are already parsed, use key.Param(i) to get them.
+// Return *NotSupported or any other error to make item not supported.
+type Handler func(key *zabbix.ItemKey) (interface{}, error)
+
+// Error for unsupported keys and parameters, sent to server as ZBX_NOTSUPPORTED reason.
+type NotSupported struct {
+	Message string
+}
+
+func (e *NotSupported) Error() string {
+	return e.Message
+}
+
+// Returns NotSupported error with formatted message.
+func NotSupportedf(format string, args ...interface{}) error {
+	return &NotSupported{fmt.Sprintf(format, args...)}
+}
+
+type registryEntry struct {
+	pattern string
+	handler Handler
+}
+
+// Registry of handlers keyed by item key pattern. Patterns are matched against key name without parameters
+// using path.Match syntax, for example "app.requests" or "app.queue.*".
+// Exact names are preferred over wildcards, wildcards are tried in order of registration.
+// Registry is safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	exact    map[string]Handler
+	patterns []registryEntry
+}
+
+// Creates registry with agent.ping and agent.version handlers.
+func NewRegistry() *Registry {
+	r := &Registry{exact: make(map[string]Handler)}
+	r.Handle("agent.ping", func(*zabbix.ItemKey) (interface{}, error) { return 1, nil })
+	r.Handle("agent.version", func(*zabbix.ItemKey) (interface{}, error) { return Version, nil })
+	return r
+}
+
+// Registers handler for pattern, replacing previous handler for the same pattern.
+func (r *Registry) Handle(pattern string, handler Handler) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("Invalid pattern %q: %s", pattern, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !hasMeta(pattern) {
+		r.exact[pattern] = handler
+		return nil
+	}
+	for i := range r.patterns {
+		if r.patterns[i].pattern == pattern {
+			r.patterns[i].handler = handler
+			return nil
+		}
+	}
+	r.patterns = append(r.patterns, registryEntry{pattern, handler})
+	return nil
+}
+
+func hasMeta(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?', '[', '\\':
+			return true
+		}
+	}
+	return false
+}
+
+// Returns handler for key name or nil.
+func (r *Registry) handler(name string) Handler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if h := r.exact[name]; h != nil {
+		return h
+	}
+	for _, e := range r.patterns {
+		if ok, _ := path.Match(e.pattern, name); ok {
+			return e.handler
+		}
+	}
+	return nil
+}
+
+// Returns value of item key formatted for server, see FormatValue. Handler panic is returned as NotSupported error.
+func (r *Registry) Get(key string) (value string, err error) {
+	k, err := zabbix.ParseItemKey(key)
+	if err != nil {
+		return "", NotSupportedf("Invalid item key format.")
+	}
+	h := r.handler(k.Name)
+	if h == nil {
+		return "", NotSupportedf("Unsupported item key.")
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			value, err = "", NotSupportedf("Handler panic: %v", p)
+		}
+	}()
+	v, err := h(k)
+	if err != nil {
+		return "", err
+	}
+	return FormatValue(v), nil
+}
+
+// Formats value as agent does: floats without exponent, booleans as 1 and 0, other values with fmt.Sprint.
+func FormatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case float32:
+		return formatFloat(float64(v), 32)
+	case float64:
+		return formatFloat(v, 64)
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func formatFloat(f float64, bits int) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprint(f)
+	}
+	return strconv.FormatFloat(f, 'f', -1, bits)
+}
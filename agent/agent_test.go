@@ -0,0 +1,140 @@
+package agent_test
+
+import (
+	. "."
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/wOvAN/zabbix"
+)
+
+func testRegistry(t *testing.T) *Registry {
+	r := NewRegistry()
+	err := r.Handle("app.queue.*", func(key *zabbix.ItemKey) (interface{}, error) {
+		if key.Param(0) == "" {
+			return nil, NotSupportedf("Queue name is required.")
+		}
+		return len(key.Param(0)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Handle("app.queue.size", func(key *zabbix.ItemKey) (interface{}, error) { return 0.25, nil })
+	r.Handle("app.up", func(key *zabbix.ItemKey) (interface{}, error) { return true, nil })
+	r.Handle("app.fail", func(key *zabbix.ItemKey) (interface{}, error) { return nil, errors.New("boom") })
+	r.Handle("app.panic", func(key *zabbix.ItemKey) (interface{}, error) { panic("oops") })
+	return r
+}
+
+func TestRegistry(t *testing.T) {
+	r := testRegistry(t)
+	for key, expected := range map[string]string{
+		"agent.ping":           "1",
+		"app.queue.size":       "0.25",
+		`app.queue.len["abc"]`: "3",
+		"app.up":               "1",
+	} {
+		v, err := r.Get(key)
+		if err != nil || v != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", key, expected, v, err)
+		}
+	}
+
+	for key, expected := range map[string]string{
+		"app.queue.len": "Queue name is required.",
+		"app.down":      "Unsupported item key.",
+		"app.up[":       "Invalid item key format.",
+		"app.fail":      "boom",
+		"app.panic":     "Handler panic: oops",
+	} {
+		_, err := r.Get(key)
+		if err == nil || err.Error() != expected {
+			t.Errorf("%s: expected error %q, got %v", key, expected, err)
+		}
+	}
+
+	if err := r.Handle("bad[", nil); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
+
+func passiveCheck(t *testing.T, addr string, request []byte) string {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err = conn.Write(request); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := zabbix.ReadPacket(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(reply)
+}
+
+func TestServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	s := NewServer(testRegistry(t))
+	s.AllowedHosts = []string{"10.0.0.1", "127.0.0.0/8"}
+	go s.Serve(l)
+
+	var b bytes.Buffer
+	zabbix.WritePacket(&b, []byte("app.queue.size"), false)
+	if reply := passiveCheck(t, l.Addr().String(), b.Bytes()); reply != "0.25" {
+		t.Errorf("Bad reply %q", reply)
+	}
+
+	if reply := passiveCheck(t, l.Addr().String(), []byte("agent.ping\n")); reply != "1" {
+		t.Errorf("Bad reply to plain text request %q", reply)
+	}
+
+	b.Reset()
+	zabbix.WritePacket(&b, []byte("app.down"), true)
+	if reply := passiveCheck(t, l.Addr().String(), b.Bytes()); !strings.HasPrefix(reply, NotSupportedReply+"\x00") {
+		t.Errorf("Bad reply for unsupported key %q", reply)
+	}
+
+	b.Reset()
+	zabbix.WritePacket(&b, []byte("app.panic"), false)
+	if reply := passiveCheck(t, l.Addr().String(), b.Bytes()); reply != NotSupportedReply+"\x00Handler panic: oops" {
+		t.Errorf("Bad reply for panicking handler %q", reply)
+	}
+
+	// only loopback is allowed by default
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l2.Close()
+	go NewServer(testRegistry(t)).Serve(l2)
+	if reply := passiveCheck(t, l2.Addr().String(), []byte("agent.ping\n")); reply != "1" {
+		t.Errorf("Bad reply from server with default allowed hosts %q", reply)
+	}
+
+	// names are resolved when server starts
+	l3, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l3.Close()
+	s3 := NewServer(testRegistry(t))
+	s3.AllowedHosts = []string{"localhost"}
+	go s3.Serve(l3)
+	if reply := passiveCheck(t, l3.Addr().String(), []byte("agent.ping\n")); reply != "1" {
+		t.Errorf("Bad reply from server allowing localhost %q", reply)
+	}
+	s3 = NewServer(testRegistry(t))
+	s3.AllowedHosts = []string{"zabbix.invalid"}
+	if err = s3.Serve(l3); err == nil {
+		t.Error("Expected error for unresolved allowed host")
+	}
+}
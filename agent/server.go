@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/wOvAN/zabbix"
+)
+
+// Reply prefix for unsupported items.
+const NotSupportedReply = "ZBX_NOTSUPPORTED"
+
+// Passive check server answering requests of Zabbix server or proxy with values from Registry.
+type Server struct {
+	Registry *Registry
+	Timeout  time.Duration // for reading request and writing reply, 3s by default like Timeout in zabbix_agentd.conf
+	Compress bool          // compress replies, only Zabbix 4.0+ servers support it
+
+	// Addresses, networks and host names allowed to connect like Server in zabbix_agentd.conf,
+	// only loopback addresses are allowed if empty. Use "0.0.0.0/0" and "::/0" to allow all.
+	// Host names are resolved once by Serve, which fails if some name can't be resolved.
+	AllowedHosts []string
+
+	ErrorLog *log.Logger // nil means log package logger
+
+	allowedNetworks []*net.IPNet // resolved AllowedHosts
+}
+
+// Creates server for registry.
+func NewServer(registry *Registry) *Server {
+	return &Server{Registry: registry, Timeout: 3 * time.Second}
+}
+
+// Listens on address like ":10050" and serves connections.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return s.Serve(l)
+}
+
+// Accepts connections and serves each in own goroutine. Returns error when listener is closed
+// or AllowedHosts can't be resolved.
+func (s *Server) Serve(l net.Listener) (err error) {
+	if s.allowedNetworks, err = s.resolveAllowedHosts(); err != nil {
+		return
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// Answers single request on connection and closes it.
+func (s *Server) ServeConn(conn net.Conn) {
+	defer conn.Close()
+	defer func() {
+		if p := recover(); p != nil {
+			s.logf("Panic serving %s: %v", conn.RemoteAddr(), p)
+		}
+	}()
+	if !s.allowed(conn.RemoteAddr()) {
+		s.logf("Connection from %s is not allowed", conn.RemoteAddr())
+		return
+	}
+	if s.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.Timeout))
+	}
+
+	request, err := zabbix.ReadPacket(conn)
+	if err != nil {
+		s.logf("Failed to read request from %s: %s", conn.RemoteAddr(), err)
+		return
+	}
+	key := strings.TrimRight(string(request), "\r\n")
+	if err = zabbix.WritePacket(conn, []byte(s.reply(key)), s.Compress); err != nil {
+		s.logf("Failed to send reply to %s: %s", conn.RemoteAddr(), err)
+	}
+}
+
+func (s *Server) reply(key string) string {
+	value, err := s.Registry.Get(key)
+	if err != nil {
+		return NotSupportedReply + "\x00" + err.Error()
+	}
+	return value
+}
+
+// Returns networks for AllowedHosts, addresses and resolved names are returned as single address networks.
+func (s *Server) resolveAllowedHosts() (res []*net.IPNet, err error) {
+	hosts := s.AllowedHosts
+	if len(hosts) == 0 {
+		hosts = []string{"127.0.0.0/8", "::1"}
+	}
+	for _, host := range hosts {
+		if _, network, err := net.ParseCIDR(host); err == nil {
+			res = append(res, network)
+			continue
+		}
+		ips := []net.IP{net.ParseIP(host)}
+		if ips[0] == nil {
+			if ips, err = net.LookupIP(host); err != nil {
+				return nil, err
+			}
+		}
+		for _, ip := range ips {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			res = append(res, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return
+}
+
+// Connections served without Serve resolve AllowedHosts each time.
+func (s *Server) allowed(addr net.Addr) bool {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	networks := s.allowedNetworks
+	if networks == nil {
+		if networks, err = s.resolveAllowedHosts(); err != nil {
+			s.logf("Failed to resolve allowed hosts: %s", err)
+			return false
+		}
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
@@ -0,0 +1,929 @@
+// configuration backup and restore
+
+package zabbix
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wOvAN/reflector"
+)
+
+// Version of backup directory layout, written to manifest.
+// LoadBackup refuses backups with greater version.
+const BackupVersion = 1
+
+// Manifest of backup directory.
+type BackupManifest struct {
+	Version       int       `json:"version"`
+	Created       time.Time `json:"created"`
+	ServerVersion string    `json:"server_version"`
+}
+
+// Snapshot of server configuration. Objects keep ids of source server, references between them
+// (groups, templates, proxies, value maps, applications, interfaces, master items and dependencies)
+// are restored by ids.
+// Only own items, triggers and applications of hosts and templates are saved, inherited and discovered ones
+// are created by server. Inherited applications used by own items are saved separately and matched by owner
+// and name on restore.
+type Backup struct {
+	Manifest              BackupManifest
+	HostGroups            HostGroups
+	ValueMaps             ValueMaps    // with Mappings
+	Templates             Templates    // with Groups, ParentTemplates and Macros
+	Hosts                 Hosts        // with GroupIds, Interfaces, ParentTemplates, Macros and Inventory
+	Applications          Applications // of hosts and templates
+	InheritedApplications Applications // used by items
+	Items                 Items        // with Preprocessing and ApplicationIds
+	Triggers              Triggers     // with expanded expressions, Hosts, Dependencies and Tags
+	Proxies               Proxys       // with Interface
+	Scripts               Scripts
+}
+
+// Files of backup directory.
+var backupFiles = []struct {
+	name string
+	data func(b *Backup) interface{}
+}{
+	{"manifest.json", func(b *Backup) interface{} { return &b.Manifest }},
+	{"hostgroups.json", func(b *Backup) interface{} { return &b.HostGroups }},
+	{"valuemaps.json", func(b *Backup) interface{} { return &b.ValueMaps }},
+	{"templates.json", func(b *Backup) interface{} { return &b.Templates }},
+	{"hosts.json", func(b *Backup) interface{} { return &b.Hosts }},
+	{"applications.json", func(b *Backup) interface{} { return &b.Applications }},
+	{"inherited_applications.json", func(b *Backup) interface{} { return &b.InheritedApplications }},
+	{"items.json", func(b *Backup) interface{} { return &b.Items }},
+	{"triggers.json", func(b *Backup) interface{} { return &b.Triggers }},
+	{"proxies.json", func(b *Backup) interface{} { return &b.Proxies }},
+	{"scripts.json", func(b *Backup) interface{} { return &b.Scripts }},
+}
+
+// Returns directory for backup created at given time, like root/20161019T150405Z.
+func BackupDir(root string, created time.Time) string {
+	return filepath.Join(root, created.UTC().Format("20060102T150405Z"))
+}
+
+// Gets configuration from server.
+func (api *API) ConfigurationBackup() (b *Backup, err error) {
+	if b, err = api.newBackup(); err != nil {
+		return
+	}
+	if b.HostGroups, err = api.HostGroupsGet(Params{}); err != nil {
+		return nil, err
+	}
+	if b.ValueMaps, err = api.ValueMapsGet(Params{SelectMappings: "extend"}); err != nil {
+		return nil, err
+	}
+	if b.Templates, err = api.TemplatesGet(backupTemplateParams(Params{})); err != nil {
+		return nil, err
+	}
+	if b.Hosts, err = api.HostsGet(backupHostParams(Params{})); err != nil {
+		return nil, err
+	}
+	if b.Proxies, err = api.ProxyGet(Params{SelectInterface: "extend"}); err != nil {
+		return nil, err
+	}
+	if b.Scripts, err = api.ScriptGet(Params{}); err != nil {
+		return nil, err
+	}
+	if err = api.backupChildren(b); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (api *API) newBackup() (b *Backup, err error) {
+	b = &Backup{Manifest: BackupManifest{Version: BackupVersion, Created: time.Now()}}
+	if b.Manifest.ServerVersion, err = api.Version(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func backupTemplateParams(params Params) Params {
+	params[SelectGroups] = []string{"groupid", "name"}
+	params[SelectParentTemplates] = []string{"templateid", "host"}
+	params[SelectMacros] = "extend"
+	return params
+}
+
+// Discovered hosts are skipped, they are created by server.
+func backupHostParams(params Params) Params {
+	params["filter"] = map[string]interface{}{"flags": 0}
+	params[SelectGroups] = []string{"groupid"}
+	params[SelectInterfaces] = "extend"
+	params[SelectParentTemplates] = []string{"templateid", "host"}
+	params[SelectMacros] = "extend"
+	params[SelectInventory] = "extend"
+	return params
+}
+
+// Returns params for own and not discovered objects of hosts and templates.
+func ownParams(params Params) Params {
+	params["inherited"] = false
+	params["filter"] = map[string]interface{}{"flags": 0}
+	return params
+}
+
+// Gets applications, items and triggers of backup hosts and templates, and value maps used by items
+// which are not in backup yet.
+func (api *API) backupChildren(b *Backup) (err error) {
+	var owners []string
+	for _, t := range b.Templates {
+		owners = append(owners, t.TemplateId)
+	}
+	for _, h := range b.Hosts {
+		owners = append(owners, h.HostId)
+	}
+	if len(owners) == 0 {
+		return
+	}
+
+	response, err := api.CallWithError("application.get", ownParams(Params{
+		"output":    "extend",
+		"hostids":   owners,
+		SelectItems: []string{"itemid"},
+	}))
+	if err != nil {
+		return
+	}
+	var links []struct {
+		ApplicationId string `json:"applicationid"`
+		Items         []struct {
+			ItemId string `json:"itemid"`
+		} `json:"items"`
+	}
+	apps := response.Result.([]interface{})
+	reflector.MapsToStructs2(apps, &b.Applications, reflector.Strconv, "json")
+	reflector.MapsToStructs2(apps, &links, reflector.Strconv, "json")
+
+	if b.Items, err = api.ItemsGet(ownParams(Params{"hostids": owners, SelectPreprocessing: "extend"})); err != nil {
+		return
+	}
+	itemApps := make(map[string][]string)
+	for _, l := range links {
+		for _, i := range l.Items {
+			itemApps[i.ItemId] = append(itemApps[i.ItemId], l.ApplicationId)
+		}
+	}
+	own := make(map[string]bool, len(b.Applications))
+	for _, a := range b.Applications {
+		own[a.ApplicationId] = true
+	}
+	var inherited []string
+	for i := range b.Items {
+		b.Items[i].ApplicationIds = itemApps[b.Items[i].ItemId]
+		for _, id := range b.Items[i].ApplicationIds {
+			if !own[id] && !containsId(inherited, id) {
+				inherited = append(inherited, id)
+			}
+		}
+	}
+	if len(inherited) > 0 {
+		b.InheritedApplications, err = api.ApplicationsGet(Params{
+			"applicationids": inherited,
+			"output":         []string{"applicationid", "hostid", "name"},
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	var valueMapIds []string
+	for _, m := range b.ValueMaps {
+		valueMapIds = append(valueMapIds, m.ValueMapId)
+	}
+	var missing []string
+	for _, item := range b.Items {
+		if item.ValueMapId != "" && item.ValueMapId != "0" && !containsId(valueMapIds, item.ValueMapId) &&
+			!containsId(missing, item.ValueMapId) {
+			missing = append(missing, item.ValueMapId)
+		}
+	}
+	if len(missing) > 0 {
+		maps, err := api.ValueMapsGet(Params{"valuemapids": missing, SelectMappings: "extend"})
+		if err != nil {
+			return err
+		}
+		b.ValueMaps = append(b.ValueMaps, maps...)
+	}
+
+	b.Triggers, err = api.TriggersGet(ownParams(Params{
+		"hostids":          owners,
+		expandExpression:   true,
+		SelectHosts:        []string{"hostid"},
+		SelectDependencies: []string{"triggerid"},
+		SelectTags:         "extend",
+	}))
+	return
+}
+
+// Writes backup to directory as JSON files, creating it if needed.
+// Files are readable by owner only, since items and macros may contain passwords.
+func (b *Backup) Save(dir string) (err error) {
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return
+	}
+	for _, f := range backupFiles {
+		data, err := json.MarshalIndent(f.data(b), "", "  ")
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, f.name), append(data, '\n'), 0600); err != nil {
+			return err
+		}
+	}
+	return
+}
+
+// Reads backup written by Save.
+func LoadBackup(dir string) (b *Backup, err error) {
+	b = new(Backup)
+	for _, f := range backupFiles {
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.name))
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(data, f.data(b)); err != nil {
+			return nil, fmt.Errorf("%s: %s", f.name, err)
+		}
+		if f.name == "manifest.json" && b.Manifest.Version > BackupVersion {
+			return nil, fmt.Errorf("Unsupported backup version %d", b.Manifest.Version)
+		}
+	}
+	return
+}
+
+// Old to new ids by kind ("hostgroup", "valuemap", "template", "host", "proxy", "application", "interface",
+// "item", "trigger" and "script"), filled when objects are copied between servers.
+type IdMap map[string]map[string]string
+
+func (m IdMap) Set(kind, oldId, newId string) {
+	if m[kind] == nil {
+		m[kind] = make(map[string]string)
+	}
+	m[kind][oldId] = newId
+}
+
+// Returns new id for old one.
+func (m IdMap) Get(kind, oldId string) (newId string, ok bool) {
+	newId, ok = m[kind][oldId]
+	return
+}
+
+// Returns new ids for old ones, error is returned for unknown ids.
+func (m IdMap) GetAll(kind string, oldIds []string) (res []string, err error) {
+	for _, id := range oldIds {
+		newId, ok := m.Get(kind, id)
+		if !ok {
+			return nil, fmt.Errorf("Unknown %s id %s", kind, id)
+		}
+		res = append(res, newId)
+	}
+	return
+}
+
+// Recreates backup on server, normally empty one, and returns ids of created objects.
+// Host groups, value maps, proxies, templates and scripts which already exist are matched by name and not changed,
+// so restore may be used with default objects of new server. Existing hosts and existing templates
+// are not changed too, together with their applications, items and triggers.
+// Triggers of existing hosts and templates are matched by description and expression, so dependencies on them
+// are restored. Dependencies on triggers missing in backup (like inherited ones) are reported by
+// DroppedDependenciesError returned after all objects are restored. User groups of scripts are not restored.
+func (api *API) ConfigurationRestore(b *Backup) (ids IdMap, err error) {
+	r := &restorer{api: api, b: b, ids: IdMap{}, skipped: make(map[string]bool)}
+	steps := []func() error{
+		r.hostGroups, r.valueMaps, r.proxies, r.templates, r.hosts, r.applications, r.inheritedApplications, r.items, r.triggers,
+		r.scripts,
+	}
+	for _, step := range steps {
+		if err = step(); err != nil {
+			return r.ids, err
+		}
+	}
+	if len(r.dropped) > 0 {
+		return r.ids, &DroppedDependenciesError{r.dropped}
+	}
+	return r.ids, nil
+}
+
+// Trigger dependencies which are not restored, like "Web is down on 13560" where 13560 is old id of trigger
+// it depends on.
+type DroppedDependenciesError struct {
+	Dropped []string
+}
+
+func (e *DroppedDependenciesError) Error() string {
+	return fmt.Sprintf("Trigger dependencies are not restored: %s", strings.Join(e.Dropped, ", "))
+}
+
+type restorer struct {
+	api     *API
+	b       *Backup
+	ids     IdMap
+	skipped map[string]bool // old ids of existing hosts and templates
+	dropped []string        // trigger dependencies which can't be restored
+}
+
+func (r *restorer) hostGroups() error {
+	existing, err := r.api.HostGroupsGet(Params{"output": []string{"groupid", "name"}})
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]string, len(existing))
+	for _, g := range existing {
+		byName[g.Name] = g.GroupId
+	}
+
+	var missing HostGroups
+	var oldIds []string
+	for _, g := range r.b.HostGroups {
+		if id, present := byName[g.Name]; present {
+			r.ids.Set("hostgroup", g.GroupId, id)
+			continue
+		}
+		missing = append(missing, HostGroup{Name: g.Name})
+		oldIds = append(oldIds, g.GroupId)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if err = r.api.HostGroupsCreate(missing); err != nil {
+		return err
+	}
+	for i, g := range missing {
+		r.ids.Set("hostgroup", oldIds[i], g.GroupId)
+	}
+	return nil
+}
+
+func (r *restorer) valueMaps() error {
+	if len(r.b.ValueMaps) == 0 {
+		return nil
+	}
+	existing, err := r.api.ValueMapsGet(Params{"output": []string{"valuemapid", "name"}})
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]string, len(existing))
+	for _, m := range existing {
+		byName[m.Name] = m.ValueMapId
+	}
+
+	var missing ValueMaps
+	var oldIds []string
+	for _, m := range r.b.ValueMaps {
+		if id, present := byName[m.Name]; present {
+			r.ids.Set("valuemap", m.ValueMapId, id)
+			continue
+		}
+		missing = append(missing, ValueMap{Name: m.Name, Mappings: m.Mappings})
+		oldIds = append(oldIds, m.ValueMapId)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if err = r.api.ValueMapsCreate(missing); err != nil {
+		return err
+	}
+	for i, m := range missing {
+		r.ids.Set("valuemap", oldIds[i], m.ValueMapId)
+	}
+	return nil
+}
+
+func (r *restorer) proxies() error {
+	existing, err := r.api.ProxyGet(Params{"output": []string{"proxyid", "host"}})
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]string, len(existing))
+	for _, p := range existing {
+		byName[p.Host] = p.ProxyId
+	}
+
+	for _, p := range r.b.Proxies {
+		if id, present := byName[p.Host]; present {
+			r.ids.Set("proxy", p.ProxyId, id)
+			continue
+		}
+		proxies := Proxys{{Host: p.Host, Description: p.Description, Status: p.Status}}
+		if iface, ok := p.Interface.(map[string]interface{}); ok && len(iface) > 0 {
+			fields := make(map[string]interface{})
+			for _, k := range []string{"useip", "ip", "dns", "port"} {
+				if v, present := iface[k]; present {
+					fields[k] = v
+				}
+			}
+			proxies[0].Interface = fields
+		}
+		if err = r.api.ProxyCreate(proxies); err != nil {
+			return err
+		}
+		r.ids.Set("proxy", p.ProxyId, proxies[0].ProxyId)
+	}
+	return nil
+}
+
+// Returns templates ordered so that linked templates go before templates they are linked to.
+func sortTemplates(templates Templates) (res Templates, err error) {
+	byId := make(map[string]Template, len(templates))
+	for _, t := range templates {
+		byId[t.TemplateId] = t
+	}
+	state := make(map[string]int) // 1 in progress, 2 done
+	var visit func(t Template) error
+	visit = func(t Template) error {
+		switch state[t.TemplateId] {
+		case 1:
+			return &TemplateCycleError{[]string{t.TemplateId, t.TemplateId}}
+		case 2:
+			return nil
+		}
+		state[t.TemplateId] = 1
+		for _, p := range t.ParentTemplates {
+			if parent, present := byId[p.TemplateId]; present {
+				if err := visit(parent); err != nil {
+					return err
+				}
+			}
+		}
+		state[t.TemplateId] = 2
+		res = append(res, t)
+		return nil
+	}
+	for _, t := range templates {
+		if err = visit(t); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+func (r *restorer) templates() error {
+	names := make([]string, len(r.b.Templates))
+	for i, t := range r.b.Templates {
+		names[i] = t.Host
+	}
+	existing := make(map[string]string)
+	if len(names) > 0 {
+		templates, err := r.api.TemplatesGet(Params{"output": []string{"templateid", "host"}, "filter": map[string]interface{}{"host": names}})
+		if err != nil {
+			return err
+		}
+		for _, t := range templates {
+			existing[t.Host] = t.TemplateId
+		}
+	}
+
+	sorted, err := sortTemplates(r.b.Templates)
+	if err != nil {
+		return err
+	}
+	for _, t := range sorted {
+		if id, present := existing[t.Host]; present {
+			r.ids.Set("template", t.TemplateId, id)
+			r.skipped[t.TemplateId] = true
+			continue
+		}
+
+		params := Params{"host": t.Host}
+		if t.Name != "" {
+			params["name"] = t.Name
+		}
+		if t.Description != "" {
+			params["description"] = t.Description
+		}
+		var groups HostGroupIds
+		for _, g := range t.Groups {
+			id, ok := r.ids.Get("hostgroup", g.GroupId)
+			if !ok {
+				return fmt.Errorf("Template %s: unknown host group %s", t.Host, g.GroupId)
+			}
+			groups = append(groups, HostGroupId{id})
+		}
+		params["groups"] = groups
+		parents, err := r.templateIds(t.ParentTemplates)
+		if err != nil {
+			return fmt.Errorf("Template %s: %s", t.Host, err)
+		}
+		if len(parents) > 0 {
+			params["templates"] = parents
+		}
+		if len(t.Macros) > 0 {
+			params["macros"] = t.Macros.WithoutIds()
+		}
+
+		response, err := r.api.CallWithError("template.create", params)
+		if err != nil {
+			return fmt.Errorf("Template %s: %s", t.Host, err)
+		}
+		ids := response.Result.(map[string]interface{})["templateids"].([]interface{})
+		r.ids.Set("template", t.TemplateId, ids[0].(string))
+	}
+	return nil
+}
+
+func (r *restorer) templateIds(templates Templates) (res TemplateIds, err error) {
+	for _, t := range templates {
+		id, ok := r.ids.Get("template", t.TemplateId)
+		if !ok {
+			return nil, fmt.Errorf("unknown template %s", t.Host)
+		}
+		res = append(res, TemplateId{id})
+	}
+	return
+}
+
+// Interfaces are matched by all fields except id.
+func interfaceKey(i HostInterface) string {
+	return fmt.Sprintf("%v|%d|%s|%s|%s", i.Type, i.Main, i.IP, i.DNS, i.Port)
+}
+
+func (r *restorer) hosts() error {
+	names := make([]string, len(r.b.Hosts))
+	for i, h := range r.b.Hosts {
+		names[i] = h.Host
+	}
+	existing := make(map[string]string)
+	if len(names) > 0 {
+		hosts, err := r.api.HostsGet(Params{"output": []string{"hostid", "host"}, "filter": map[string]interface{}{"host": names}})
+		if err != nil {
+			return err
+		}
+		for _, h := range hosts {
+			existing[h.Host] = h.HostId
+		}
+	}
+
+	for _, h := range r.b.Hosts {
+		if id, present := existing[h.Host]; present {
+			r.ids.Set("host", h.HostId, id)
+			r.skipped[h.HostId] = true
+			continue
+		}
+
+		params := Params{"host": h.Host, "name": h.Name, "status": h.Status}
+		for k, v := range map[string]string{
+			"description":      h.Description,
+			"ipmi_authtype":    h.IPMIAuthType,
+			"ipmi_privilege":   h.IPMIPrivilege,
+			"ipmi_username":    h.IPMIUsername,
+			"ipmi_password":    h.IPMIPassword,
+			"tls_connect":      h.TLSConnect,
+			"tls_accept":       h.TLSAccept,
+			"tls_issuer":       h.TLSIssuer,
+			"tls_subject":      h.TLSSubject,
+			"tls_psk_identity": h.TLSPSKIdentity,
+			"tls_psk":          h.TLSPSK,
+		} {
+			if v != "" {
+				params[k] = v
+			}
+		}
+		var groups HostGroupIds
+		for _, g := range h.GroupIds {
+			id, ok := r.ids.Get("hostgroup", g.GroupId)
+			if !ok {
+				return fmt.Errorf("Host %s: unknown host group %s", h.Host, g.GroupId)
+			}
+			groups = append(groups, HostGroupId{id})
+		}
+		params["groups"] = groups
+		interfaces := make(HostInterfaces, len(h.Interfaces))
+		for i, iface := range h.Interfaces {
+			iface.InterfaceId = ""
+			interfaces[i] = iface
+		}
+		params["interfaces"] = interfaces
+		templates, err := r.templateIds(h.ParentTemplates)
+		if err != nil {
+			return fmt.Errorf("Host %s: %s", h.Host, err)
+		}
+		if len(templates) > 0 {
+			params["templates"] = templates
+		}
+		if h.ProxyHostID != "" && h.ProxyHostID != "0" {
+			id, ok := r.ids.Get("proxy", h.ProxyHostID)
+			if !ok {
+				return fmt.Errorf("Host %s: unknown proxy %s", h.Host, h.ProxyHostID)
+			}
+			params["proxy_hostid"] = id
+		}
+		if len(h.Macros) > 0 {
+			params["macros"] = h.Macros.WithoutIds()
+		}
+		if inventory, ok := h.Inventory.(map[string]interface{}); ok && len(inventory) > 0 {
+			fields := make(map[string]interface{}, len(inventory))
+			for k, v := range inventory {
+				switch k {
+				case "hostid":
+				case "inventory_mode":
+					params[k] = v
+				default:
+					fields[k] = v
+				}
+			}
+			params["inventory"] = fields
+		}
+
+		response, err := r.api.CallWithError("host.create", params)
+		if err != nil {
+			return fmt.Errorf("Host %s: %s", h.Host, err)
+		}
+		id := response.Result.(map[string]interface{})["hostids"].([]interface{})[0].(string)
+		r.ids.Set("host", h.HostId, id)
+
+		if len(h.Interfaces) == 0 {
+			continue
+		}
+		created, err := r.api.HostsGet(Params{"hostids": id, "output": []string{"hostid"}, SelectInterfaces: "extend"})
+		if err != nil {
+			return err
+		}
+		newIds := make(map[string]string)
+		for _, c := range created {
+			for _, iface := range c.Interfaces {
+				newIds[interfaceKey(iface)] = iface.InterfaceId
+			}
+		}
+		for _, iface := range h.Interfaces {
+			if newId, ok := newIds[interfaceKey(iface)]; ok {
+				r.ids.Set("interface", iface.InterfaceId, newId)
+			}
+		}
+	}
+	return nil
+}
+
+// Returns new id of owner (host or template) unless it is skipped.
+func (r *restorer) ownerId(oldId string) (id string, ok bool) {
+	if r.skipped[oldId] {
+		return "", false
+	}
+	if id, ok = r.ids.Get("template", oldId); ok {
+		return
+	}
+	return r.ids.Get("host", oldId)
+}
+
+func (r *restorer) applications() error {
+	var apps Applications
+	var oldIds []string
+	for _, a := range r.b.Applications {
+		owner, ok := r.ownerId(a.HostId)
+		if !ok {
+			continue
+		}
+		apps = append(apps, Application{HostId: owner, Name: a.Name})
+		oldIds = append(oldIds, a.ApplicationId)
+	}
+	if len(apps) == 0 {
+		return nil
+	}
+	if err := r.api.ApplicationsCreate(apps); err != nil {
+		return err
+	}
+	for i, a := range apps {
+		r.ids.Set("application", oldIds[i], a.ApplicationId)
+	}
+	return nil
+}
+
+// Inherited applications are created by server when templates are linked, so they are matched by owner and name.
+func (r *restorer) inheritedApplications() error {
+	var owners []string
+	for _, a := range r.b.InheritedApplications {
+		if owner, ok := r.ownerId(a.HostId); ok && !containsId(owners, owner) {
+			owners = append(owners, owner)
+		}
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+	existing, err := r.api.ApplicationsGet(Params{"hostids": owners, "output": []string{"applicationid", "hostid", "name"}})
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]string, len(existing))
+	for _, a := range existing {
+		byName[a.HostId+"\x00"+a.Name] = a.ApplicationId
+	}
+	for _, a := range r.b.InheritedApplications {
+		owner, ok := r.ownerId(a.HostId)
+		if !ok {
+			continue
+		}
+		id, ok := byName[owner+"\x00"+a.Name]
+		if !ok {
+			return fmt.Errorf("Inherited application %s of host %s is not found", a.Name, a.HostId)
+		}
+		r.ids.Set("application", a.ApplicationId, id)
+	}
+	return nil
+}
+
+func (r *restorer) items() error {
+	// items are created per owner, since dependent items refer to masters by key
+	var owners []string
+	byOwner := make(map[string]Items)
+	for _, item := range r.b.Items {
+		if _, ok := r.ownerId(item.HostId); !ok {
+			continue
+		}
+		if _, present := byOwner[item.HostId]; !present {
+			owners = append(owners, item.HostId)
+		}
+		byOwner[item.HostId] = append(byOwner[item.HostId], item)
+	}
+	sort.Strings(owners)
+
+	for _, owner := range owners {
+		old := byOwner[owner]
+		keys := make(map[string]string, len(old))
+		for _, item := range old {
+			keys[item.ItemId] = item.Key
+		}
+
+		newOwner, _ := r.ownerId(owner)
+		items := make(Items, len(old))
+		masterKeys := make(map[string]string)
+		for i, item := range old {
+			item.ItemId = ""
+			item.TemplateId = ""
+			item.Error = ""
+			item.HostId = newOwner
+			if item.InterfaceId != "" && item.InterfaceId != "0" {
+				id, ok := r.ids.Get("interface", item.InterfaceId)
+				if !ok {
+					return fmt.Errorf("Item %s: unknown interface %s", item.Key, item.InterfaceId)
+				}
+				item.InterfaceId = id
+			}
+			if item.ValueMapId != "" && item.ValueMapId != "0" {
+				id, ok := r.ids.Get("valuemap", item.ValueMapId)
+				if !ok {
+					return fmt.Errorf("Item %s: unknown value map %s", item.Key, item.ValueMapId)
+				}
+				item.ValueMapId = id
+			}
+			apps, err := r.ids.GetAll("application", item.ApplicationIds)
+			if err != nil {
+				return fmt.Errorf("Item %s: %s", item.Key, err)
+			}
+			item.ApplicationIds = apps
+			if item.MasterItemId != "" && item.MasterItemId != "0" {
+				key, ok := keys[item.MasterItemId]
+				if !ok {
+					return fmt.Errorf("Item %s: unknown master item %s", item.Key, item.MasterItemId)
+				}
+				masterKeys[item.Key] = key
+			}
+			item.MasterItemId = ""
+			items[i] = item
+		}
+
+		if err := r.api.ItemsCreateOrdered(items, masterKeys); err != nil {
+			return err
+		}
+		for i, item := range items {
+			r.ids.Set("item", old[i].ItemId, item.ItemId)
+		}
+	}
+	return nil
+}
+
+// Maps triggers of existing hosts and templates to triggers with the same description and expression.
+// Expanded expressions refer to hosts by name, which is the same on both servers.
+func (r *restorer) existingTriggers(triggers Triggers) error {
+	var owners []string
+	for _, t := range triggers {
+		for _, h := range t.Hosts {
+			id, ok := r.ids.Get("template", h.HostId)
+			if !ok {
+				id, ok = r.ids.Get("host", h.HostId)
+			}
+			if ok && !containsId(owners, id) {
+				owners = append(owners, id)
+			}
+		}
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+	existing, err := r.api.TriggersGet(Params{
+		"hostids":        owners,
+		"output":         []string{"triggerid", "description", "expression"},
+		expandExpression: true,
+	})
+	if err != nil {
+		return err
+	}
+	byExpression := make(map[string]string, len(existing))
+	for _, t := range existing {
+		byExpression[t.Description+"\x00"+normalizeExpression(t.Expression)] = t.TriggerId
+	}
+	for _, t := range triggers {
+		if id, ok := byExpression[t.Description+"\x00"+normalizeExpression(t.Expression)]; ok {
+			r.ids.Set("trigger", t.TriggerId, id)
+		}
+	}
+	return nil
+}
+
+func (r *restorer) triggers() error {
+	var triggers Triggers
+	var old, existing Triggers
+outer:
+	for _, t := range r.b.Triggers {
+		for _, h := range t.Hosts {
+			if _, ok := r.ownerId(h.HostId); !ok {
+				existing = append(existing, t)
+				continue outer
+			}
+		}
+		old = append(old, t)
+		t.TriggerId = ""
+		t.Error = ""
+		t.LastChange = ""
+		t.State = ""
+		t.TemplateId = ""
+		t.Value = ""
+		t.Functions = nil
+		t.Groups = nil
+		t.Hosts = nil
+		t.Items = nil
+		t.Dependencies = nil
+		triggers = append(triggers, t)
+	}
+	if len(triggers) == 0 {
+		return nil
+	}
+	if err := r.existingTriggers(existing); err != nil {
+		return err
+	}
+	if err := r.api.TriggersCreate(triggers); err != nil {
+		return err
+	}
+	for i, t := range triggers {
+		r.ids.Set("trigger", old[i].TriggerId, t.TriggerId)
+	}
+
+	for i, t := range old {
+		var deps []string
+		for _, d := range t.Dependencies {
+			if id, ok := r.ids.Get("trigger", d.TriggerId); ok {
+				deps = append(deps, id)
+			} else {
+				r.dropped = append(r.dropped, fmt.Sprintf("%s on %s", t.Description, d.TriggerId))
+			}
+		}
+		if len(deps) == 0 {
+			continue
+		}
+		if err := r.api.TriggersAddDependencies(triggers[i].TriggerId, deps); err != nil {
+			return fmt.Errorf("Trigger %s: %s", t.Description, err)
+		}
+	}
+	return nil
+}
+
+func (r *restorer) scripts() error {
+	existing, err := r.api.ScriptGet(Params{"output": []string{"scriptid", "name"}})
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]string, len(existing))
+	for _, s := range existing {
+		byName[s.Name] = s.ScriptId
+	}
+
+	for _, s := range r.b.Scripts {
+		if id, present := byName[s.Name]; present {
+			r.ids.Set("script", s.ScriptId, id)
+			continue
+		}
+		oldId := s.ScriptId
+		s.ScriptId = ""
+		s.UsrGrpId = ""
+		if s.GroupId != "" && s.GroupId != "0" {
+			id, ok := r.ids.Get("hostgroup", s.GroupId)
+			if !ok {
+				return fmt.Errorf("Script %s: unknown host group %s", s.Name, s.GroupId)
+			}
+			s.GroupId = id
+		}
+		scripts := Scripts{s}
+		if err = r.api.ScriptCreate(scripts); err != nil {
+			return fmt.Errorf("Script %s: %s", s.Name, err)
+		}
+		r.ids.Set("script", oldId, scripts[0].ScriptId)
+	}
+	return nil
+}
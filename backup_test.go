@@ -0,0 +1,210 @@
+package zabbix_test
+
+import (
+	. "."
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackupSaveLoad(t *testing.T) {
+	root, err := ioutil.TempDir("", "zabbix-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	created := time.Date(2016, 10, 19, 15, 4, 5, 0, time.UTC)
+	b := &Backup{
+		Manifest:   BackupManifest{Version: BackupVersion, Created: created, ServerVersion: "4.0.0"},
+		HostGroups: HostGroups{{GroupId: "2", Name: "Linux servers"}},
+		Hosts: Hosts{{HostId: "10", Host: "web", Name: "web", GroupIds: HostGroupIds{{"2"}},
+			Macros: HostMacros{{HostMacroId: "1", HostId: "10", Macro: "{$PORT}", Value: "80"}}}},
+		Items:                 Items{{ItemId: "20", HostId: "10", Key: "agent.ping", Delay: "1m", ApplicationIds: []string{"30"}}},
+		InheritedApplications: Applications{{ApplicationId: "30", HostId: "10", Name: "Agent"}},
+		Proxies: Proxys{{ProxyId: "40", Host: "proxy", Status: PassiveProxy,
+			Interface: map[string]interface{}{"useip": "1", "ip": "10.0.0.1", "dns": "", "port": "10051"}}},
+	}
+	dir := BackupDir(root, created)
+	if dir != root+"/20161019T150405Z" {
+		t.Errorf("Bad dir %s", dir)
+	}
+	if err = b.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadBackup(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Manifest.Created.Equal(created) || !reflect.DeepEqual(loaded.Hosts, b.Hosts) ||
+		!reflect.DeepEqual(loaded.Items[0].ApplicationIds, b.Items[0].ApplicationIds) ||
+		!reflect.DeepEqual(loaded.Proxies, b.Proxies) ||
+		!reflect.DeepEqual(loaded.InheritedApplications, b.InheritedApplications) || loaded.Templates != nil {
+		t.Errorf("Bad loaded backup %#v", loaded)
+	}
+	if macros := b.Hosts[0].Macros.WithoutIds(); macros[0].HostMacroId != "" || macros[0].HostId != "" {
+		t.Errorf("Bad macros %#v", macros)
+	}
+
+	b.Manifest.Version = BackupVersion + 1
+	if err = b.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadBackup(dir); err == nil {
+		t.Error("Expected error for newer backup version")
+	}
+}
+
+func TestIdMap(t *testing.T) {
+	ids := IdMap{}
+	ids.Set("host", "1", "101")
+	ids.Set("host", "2", "102")
+	if id, ok := ids.Get("host", "1"); !ok || id != "101" {
+		t.Errorf("Bad id %s", id)
+	}
+	if _, ok := ids.Get("item", "1"); ok {
+		t.Error("Unexpected item id")
+	}
+	res, err := ids.GetAll("host", []string{"2", "1"})
+	if err != nil || !reflect.DeepEqual(res, []string{"102", "101"}) {
+		t.Errorf("Bad ids %v %v", res, err)
+	}
+	if _, err = ids.GetAll("host", []string{"3"}); err == nil {
+		t.Error("Expected error for unknown id")
+	}
+}
+
+func TestDroppedDependenciesError(t *testing.T) {
+	err := &DroppedDependenciesError{[]string{"Web is down on 13560", "DB is down on 13561"}}
+	if err.Error() != "Trigger dependencies are not restored: Web is down on 13560, DB is down on 13561" {
+		t.Errorf("Bad error %q", err)
+	}
+}
+
+func TestConfigurationRestoreItems(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zabbix-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := &Backup{
+		HostGroups: HostGroups{{GroupId: "2", Name: "Switches"}},
+		ValueMaps:  ValueMaps{{ValueMapId: "5", Name: "ifOperStatus", Mappings: ValueMappings{{"1", "up"}, {"2", "down"}}}},
+		Hosts: Hosts{{HostId: "10", Host: "switch", Name: "switch", Description: "Core switch", TLSConnect: "1",
+			GroupIds:   HostGroupIds{{"2"}},
+			Interfaces: HostInterfaces{{InterfaceId: "30", IP: "10.0.0.1", Main: 1, Port: "161", Type: SNMP, UseIP: 1}}}},
+		Items: Items{
+			{ItemId: "20", HostId: "10", Key: "ifOperStatus[1]", Type: SNMPv2Agent, ValueType: Unsigned, Delay: "1m",
+				InterfaceId: "30", SNMPOid: "IF-MIB::ifOperStatus.1", SNMPCommunity: "{$SNMP_COMMUNITY}", ValueMapId: "5"},
+			{ItemId: "21", HostId: "10", Key: "traffic", Type: Calculated, ValueType: Float, Delay: "1m", Units: "bps",
+				Params: `last("ifInOctets[1]")+last("ifOutOctets[1]")`, ValueMapId: "0"},
+		},
+	}
+	if err = b.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = LoadBackup(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var created []interface{}
+	api := stubAPI(func(method string, params interface{}) interface{} {
+		p, _ := params.(map[string]interface{})
+		switch method {
+		case "hostgroup.get", "valuemap.get", "proxy.get", "script.get":
+			return []interface{}{}
+		case "hostgroup.create":
+			return map[string]interface{}{"groupids": []interface{}{"102"}}
+		case "valuemap.create":
+			return map[string]interface{}{"valuemapids": []interface{}{"105"}}
+		case "host.get":
+			if p["hostids"] == nil {
+				return []interface{}{}
+			}
+			return []interface{}{map[string]interface{}{"hostid": "110", "interfaces": []interface{}{map[string]interface{}{
+				"interfaceid": "130", "dns": "", "ip": "10.0.0.1", "main": 1, "port": "161", "type": 2, "useip": 1}}}}
+		case "host.create":
+			if p["description"] != "Core switch" || p["tls_connect"] != "1" {
+				t.Errorf("Bad host %v", p)
+			}
+			return map[string]interface{}{"hostids": []interface{}{"110"}}
+		case "item.create":
+			created = append(created, params.([]interface{})...)
+			return map[string]interface{}{"itemids": []interface{}{"120", "121"}}
+		}
+		t.Errorf("Unexpected call %s", method)
+		return []interface{}{}
+	})
+	ids, err := api.ConfigurationRestore(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := ids.Get("valuemap", "5"); id != "105" {
+		t.Errorf("Value map is not restored: %v", ids)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"hostid": "110", "interfaceid": "130", "key_": "ifOperStatus[1]", "name": "",
+			"type": float64(4), "value_type": float64(3), "description": "", "status": float64(0), "delay": "1m",
+			"snmp_oid": "IF-MIB::ifOperStatus.1", "snmp_community": "{$SNMP_COMMUNITY}", "valuemapid": "105"},
+		map[string]interface{}{"hostid": "110", "key_": "traffic", "name": "",
+			"type": float64(15), "value_type": float64(0), "description": "", "status": float64(0), "delay": "1m",
+			"units": "bps", "params": `last("ifInOctets[1]")+last("ifOutOctets[1]")`, "valuemapid": "0"},
+	}
+	if !reflect.DeepEqual(created, expected) {
+		t.Errorf("Expected items\n%v\ngot\n%v", expected, created)
+	}
+}
+
+func TestConfigurationBackupRestore(t *testing.T) {
+	api := getAPI(t)
+
+	group := CreateHostGroup(t)
+	defer DeleteHostGroup(group, t)
+	host := CreateHost(group, t)
+	app := CreateApplication(host, t)
+	item := CreateItem(app, t)
+
+	b, err := api.ConfigurationBackup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, i := range b.Items {
+		if i.ItemId == item.ItemId {
+			found = reflect.DeepEqual(i.ApplicationIds, []string{app.ApplicationId})
+		}
+	}
+	if !found {
+		t.Fatalf("Item %s with application is not in backup", item.ItemId)
+	}
+
+	// restore removed host, other objects exist and are kept
+	DeleteHost(host, t)
+	ids, err := api.ConfigurationRestore(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newHostId, ok := ids.Get("host", host.HostId)
+	if !ok {
+		t.Fatalf("Host is not restored: %v", ids)
+	}
+	defer api.HostsDeleteByIds([]string{newHostId})
+	if groupId, _ := ids.Get("hostgroup", group.GroupId); groupId != group.GroupId {
+		t.Errorf("Existing group is not matched: %v", ids)
+	}
+	newItemId, ok := ids.Get("item", item.ItemId)
+	if !ok {
+		t.Fatalf("Item is not restored: %v", ids)
+	}
+	items, err := api.ItemsGet(Params{"itemids": newItemId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].HostId != newHostId || items[0].Key != item.Key {
+		t.Errorf("Bad restored items %#v", items)
+	}
+}
@@ -2,6 +2,9 @@ package zabbix_test
 
 import (
 	. "."
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
 	"log"
 	"math/rand"
 	"net/http"
@@ -61,6 +64,41 @@ func getAPI(t *testing.T) *API {
 	return _api
 }
 
+// Transport answering API calls with results of handler, for tests which don't need server.
+// Handler may return *Error to fail call.
+type stubTransport func(method string, params interface{}) interface{}
+
+func (handle stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	defer req.Body.Close()
+	var request struct {
+		Method string      `json:"method"`
+		Params interface{} `json:"params"`
+		Id     int32       `json:"id"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		return nil, err
+	}
+	response := Response{Jsonrpc: "2.0", Id: request.Id}
+	result := handle(request.Method, request.Params)
+	if e, ok := result.(*Error); ok {
+		response.Error = e
+	} else {
+		response.Result = result
+	}
+	b, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: 200, Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewReader(b)), Request: req}, nil
+}
+
+// Returns API calling handler instead of server.
+func stubAPI(handler func(method string, params interface{}) interface{}) *API {
+	api := NewAPI("http://zabbix.invalid/api_jsonrpc.php")
+	api.SetClient(&http.Client{Transport: stubTransport(handler)})
+	return api
+}
+
 func TestBadCalls(t *testing.T) {
 	api := getAPI(t)
 	res, err := api.Call("", nil)
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wOvAN/zabbix"
+)
+
+// Returns flag set of command, errors are reported by run together with command usage.
+func newFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("zbx "+name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return fs
+}
+
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	return nil
+}
+
+// Splits comma separated list, skipping empty elements.
+func splitList(s string) (res []string) {
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			res = append(res, e)
+		}
+	}
+	return
+}
+
+// Error for missing object.
+type notFoundError struct {
+	kind, name string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("%s %q not found", e.kind, e.name)
+}
+
+func notFound(kind, name string) error {
+	return &notFoundError{kind, name}
+}
+
+// Returns ids of objects with given names from get function, error is returned for missing ones.
+func resolve(kind string, names []string, get func(names []string) (map[string]string, error)) (res []string, err error) {
+	if len(names) == 0 {
+		return
+	}
+	ids, err := get(names)
+	if err != nil {
+		return
+	}
+	for _, name := range names {
+		id, ok := ids[name]
+		if !ok {
+			return nil, notFound(kind, name)
+		}
+		res = append(res, id)
+	}
+	return
+}
+
+func groupIds(api *zabbix.API, names []string) ([]string, error) {
+	return resolve("Host group", names, func(names []string) (map[string]string, error) {
+		groups, err := api.HostGroupsGet(zabbix.Params{"output": []string{"groupid", "name"}, "filter": zabbix.Params{"name": names}})
+		ids := make(map[string]string)
+		for _, g := range groups {
+			ids[g.Name] = g.GroupId
+		}
+		return ids, err
+	})
+}
+
+func templateIds(api *zabbix.API, names []string) ([]string, error) {
+	return resolve("Template", names, func(names []string) (map[string]string, error) {
+		templates, err := api.TemplatesGet(zabbix.Params{"output": []string{"templateid", "host"}, "filter": zabbix.Params{"host": names}})
+		ids := make(map[string]string)
+		for _, t := range templates {
+			ids[t.Host] = t.TemplateId
+		}
+		return ids, err
+	})
+}
+
+func hostIds(api *zabbix.API, names []string) ([]string, error) {
+	return resolve("Host", names, func(names []string) (map[string]string, error) {
+		hosts, err := api.HostsGet(zabbix.Params{"output": []string{"hostid", "host"}, "filter": zabbix.Params{"host": names}})
+		ids := make(map[string]string)
+		for _, h := range hosts {
+			ids[h.Host] = h.HostId
+		}
+		return ids, err
+	})
+}
+
+func proxyId(api *zabbix.API, name string) (string, error) {
+	ids, err := resolve("Proxy", []string{name}, func(names []string) (map[string]string, error) {
+		proxies, err := api.ProxyGet(zabbix.Params{"output": []string{"proxyid", "host"}, "filter": zabbix.Params{"host": names}})
+		ids := make(map[string]string)
+		for _, p := range proxies {
+			ids[p.Host] = p.ProxyId
+		}
+		return ids, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+func hostStatus(s zabbix.StatusType) string {
+	if s == zabbix.Unmonitored {
+		return "unmonitored"
+	}
+	return "monitored"
+}
+
+func hostAvailable(a zabbix.AvailableType) string {
+	switch a {
+	case zabbix.Available:
+		return "available"
+	case zabbix.Unavailable:
+		return "unavailable"
+	}
+	return "unknown"
+}
+
+func hostList(e *env, args []string) error {
+	fs := newFlags("host list")
+	group := fs.String("group", "", "host group name")
+	search := fs.String("search", "", "part of host name")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	api, err := e.API()
+	if err != nil {
+		return err
+	}
+
+	params := zabbix.Params{"sortfield": "host"}
+	if *group != "" {
+		if params["groupids"], err = groupIds(api, []string{*group}); err != nil {
+			return err
+		}
+	}
+	if *search != "" {
+		params["search"] = zabbix.Params{"host": *search}
+	}
+	hosts, err := api.HostsGet(params)
+	if err != nil {
+		return err
+	}
+
+	t := &table{columns: []string{"hostid", "host", "name", "status", "available"}, objects: hosts}
+	for _, h := range hosts {
+		t.add(h.HostId, h.Host, h.Name, hostStatus(h.Status), hostAvailable(h.Available))
+	}
+	return e.print(t)
+}
+
+func hostGet(e *env, args []string) error {
+	fs := newFlags("host get")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usagef("Expected one host")
+	}
+	api, err := e.API()
+	if err != nil {
+		return err
+	}
+
+	hosts, err := api.HostsGet(zabbix.Params{
+		"filter":                     zabbix.Params{"host": fs.Arg(0)},
+		zabbix.SelectInterfaces:      "extend",
+		zabbix.SelectParentTemplates: []string{"templateid", "host"},
+		zabbix.SelectMacros:          "extend",
+	})
+	if err != nil {
+		return err
+	}
+	if len(hosts) != 1 {
+		return notFound("Host", fs.Arg(0))
+	}
+	h := hosts[0]
+
+	var interfaces, templates []string
+	for _, i := range h.Interfaces {
+		address := i.DNS
+		if i.UseIP == 1 {
+			address = i.IP
+		}
+		interfaces = append(interfaces, address+":"+i.Port)
+	}
+	for _, p := range h.ParentTemplates {
+		templates = append(templates, p.Host)
+	}
+	t := &table{columns: []string{"hostid", "host", "name", "status", "available", "interfaces", "templates", "error"}, objects: h}
+	t.add(h.HostId, h.Host, h.Name, hostStatus(h.Status), hostAvailable(h.Available),
+		strings.Join(interfaces, ","), strings.Join(templates, ","), h.Error)
+	return e.print(t)
+}
+
+func hostCreate(e *env, args []string) error {
+	fs := newFlags("host create")
+	groups := fs.String("group", "", "comma separated host group names")
+	name := fs.String("name", "", "visible name")
+	ip := fs.String("ip", "", "agent interface IP address")
+	dns := fs.String("dns", "", "agent interface DNS name")
+	port := fs.String("port", "10050", "agent interface port")
+	templates := fs.String("template", "", "comma separated template names")
+	proxy := fs.String("proxy", "", "proxy name")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usagef("Expected one host")
+	}
+	if len(splitList(*groups)) == 0 {
+		return usagef("At least one --group is required")
+	}
+	if *ip != "" && *dns != "" {
+		return usagef("Only one of --ip and --dns may be set")
+	}
+	api, err := e.API()
+	if err != nil {
+		return err
+	}
+
+	host := zabbix.Host{Host: fs.Arg(0), Name: *name}
+	ids, err := groupIds(api, splitList(*groups))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		host.GroupIds = append(host.GroupIds, zabbix.HostGroupId{GroupId: id})
+	}
+	if ids, err = templateIds(api, splitList(*templates)); err != nil {
+		return err
+	}
+	host.Templates = zabbix.NewTemplateIds(ids...)
+	if *proxy != "" {
+		if host.ProxyHostID, err = proxyId(api, *proxy); err != nil {
+			return err
+		}
+	}
+	iface := zabbix.HostInterface{Type: zabbix.Agent, Main: 1, UseIP: 1, IP: *ip, Port: *port}
+	if *ip == "" {
+		iface.UseIP = 0
+		iface.DNS = *dns
+		if iface.DNS == "" {
+			iface.DNS = host.Host
+		}
+	}
+	host.Interfaces = zabbix.HostInterfaces{iface}
+
+	hosts := zabbix.Hosts{host}
+	if err = api.HostsCreate(hosts); err != nil {
+		return err
+	}
+	t := &table{columns: []string{"hostid", "host"}, objects: hosts[0]}
+	t.add(hosts[0].HostId, hosts[0].Host)
+	return e.print(t)
+}
+
+func hostDelete(e *env, args []string) error {
+	fs := newFlags("host delete")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usagef("Expected hosts")
+	}
+	api, err := e.API()
+	if err != nil {
+		return err
+	}
+
+	ids, err := hostIds(api, fs.Args())
+	if err != nil {
+		return err
+	}
+	if err = api.HostsDeleteByIds(ids); err != nil {
+		return err
+	}
+	t := &table{columns: []string{"hostid", "host"}, objects: zabbix.NewHostIds(ids...)}
+	for i, id := range ids {
+		t.add(id, fs.Arg(i))
+	}
+	return e.print(t)
+}
+
+func itemList(e *env, args []string) error {
+	fs := newFlags("item list")
+	host := fs.String("host", "", "host name")
+	key := fs.String("key", "", "key pattern, * matches any string")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *host == "" {
+		return usagef("--host is required")
+	}
+	api, err := e.API()
+	if err != nil {
+		return err
+	}
+
+	ids, err := hostIds(api, []string{*host})
+	if err != nil {
+		return err
+	}
+	params := zabbix.Params{"hostids": ids, "sortfield": "key_"}
+	if *key != "" {
+		params["search"] = zabbix.Params{"key_": *key}
+		params["searchWildcardsEnabled"] = true
+	}
+	items, err := api.ItemsGet(params)
+	if err != nil {
+		return err
+	}
+
+	t := &table{columns: []string{"itemid", "key", "name", "type", "delay", "status"}, objects: items}
+	for _, i := range items {
+		status := "enabled"
+		if i.Status == zabbix.ItemDisabled {
+			status = "disabled"
+		}
+		t.add(i.ItemId, i.Key, i.Name, zabbix.ItemTypeToText(i.Type), i.Delay, status)
+	}
+	return e.print(t)
+}
+
+func triggerList(e *env, args []string) error {
+	fs := newFlags("trigger list")
+	host := fs.String("host", "", "host name")
+	problem := fs.Bool("problem", false, "only triggers in problem state")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	api, err := e.API()
+	if err != nil {
+		return err
+	}
+
+	params := zabbix.Params{"sortfield": "description", zabbix.SelectHosts: []string{"hostid", "host"}}
+	if *host != "" {
+		if params["hostids"], err = hostIds(api, []string{*host}); err != nil {
+			return err
+		}
+	}
+	if *problem {
+		params["filter"] = zabbix.Params{"value": 1}
+	}
+	triggers, err := api.TriggersGetExpanded(params)
+	if err != nil {
+		return err
+	}
+
+	t := &table{columns: []string{"triggerid", "description", "priority", "status", "value", "hosts"}, objects: triggers}
+	for _, tr := range triggers {
+		value := "ok"
+		if tr.Value == "1" {
+			value = "problem"
+		}
+		var hosts []string
+		for _, h := range tr.Hosts {
+			hosts = append(hosts, h.Host)
+		}
+		t.add(tr.TriggerId, tr.Description, zabbix.TriggerPriorityToText(tr.Priority), zabbix.TriggerStatusToText(tr.Status),
+			value, strings.Join(hosts, ","))
+	}
+	return e.print(t)
+}
+
+func templateExport(e *env, args []string) error {
+	fs := newFlags("template export")
+	format := fs.String("format", "", "xml, json or yaml, by file extension or xml by default")
+	file := fs.String("file", "", "output file, standard output by default")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usagef("Expected templates")
+	}
+	f := zabbix.ConfigurationFormat(*format)
+	switch {
+	case *format != "":
+		if f != zabbix.ConfigurationXML && f != zabbix.ConfigurationJSON && f != zabbix.ConfigurationYAML {
+			return usagef("Unknown format %q", *format)
+		}
+	case *file != "":
+		var err error
+		if f, err = zabbix.ConfigurationFormatFromFile(*file); err != nil {
+			return usageError(err.Error())
+		}
+	default:
+		f = zabbix.ConfigurationXML
+	}
+	api, err := e.API()
+	if err != nil {
+		return err
+	}
+
+	ids, err := templateIds(api, fs.Args())
+	if err != nil {
+		return err
+	}
+	source, err := api.TemplatesExport(f, ids)
+	if err != nil {
+		return err
+	}
+	if *file != "" {
+		return ioutil.WriteFile(*file, []byte(source), 0644)
+	}
+	_, err = fmt.Fprintln(e.stdout, source)
+	return err
+}
+
+func templateImport(e *env, args []string) error {
+	fs := newFlags("template import")
+	deleteMissing := fs.Bool("delete-missing", false, "delete template objects missing in file")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usagef("Expected one file")
+	}
+	if _, err := zabbix.ConfigurationFormatFromFile(fs.Arg(0)); err != nil {
+		return usageError(err.Error())
+	}
+	api, err := e.API()
+	if err != nil {
+		return err
+	}
+	return api.ConfigurationImportFile(fs.Arg(0), zabbix.FullImportRules(*deleteMissing))
+}
+
+func scriptRun(e *env, args []string) error {
+	fs := newFlags("script run")
+	host := fs.String("host", "", "host name")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *host == "" || fs.NArg() != 1 {
+		return usagef("Expected --host and one script")
+	}
+	api, err := e.API()
+	if err != nil {
+		return err
+	}
+
+	hosts, err := hostIds(api, []string{*host})
+	if err != nil {
+		return err
+	}
+	scripts, err := api.ScriptGet(zabbix.Params{"output": []string{"scriptid", "name"}, "filter": zabbix.Params{"name": fs.Arg(0)}})
+	if err != nil {
+		return err
+	}
+	if len(scripts) != 1 {
+		return notFound("Script", fs.Arg(0))
+	}
+
+	response, output, err := api.ScriptExecute(scripts[0].ScriptId, hosts[0])
+	if err != nil {
+		return err
+	}
+	if e.format == "json" || e.format == "yaml" {
+		err = e.print(&table{objects: map[string]string{"response": response, "value": output}})
+	} else {
+		_, err = fmt.Fprintln(e.stdout, output)
+	}
+	if err == nil && response != zabbix.ScriptExecRespSuccess {
+		err = fmt.Errorf("Script %s failed", fs.Arg(0))
+	}
+	return err
+}
+
+var unixTimeRE = regexp.MustCompile(`^[0-9]+$`)
+
+// Parses time as "now", duration ago like "1h" or "-1h", unix time, RFC 3339 or local "2006-01-02 15:04:05",
+// "2006-01-02 15:04" or "2006-01-02".
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "now" {
+		return now, nil
+	}
+	if unixTimeRE.MatchString(s) {
+		sec, err := strconv.ParseInt(s, 10, 64)
+		return time.Unix(sec, 0), err
+	}
+	if u, err := zabbix.ParseTimeUnit(strings.TrimPrefix(s, "-")); err == nil && !u.IsMacro() {
+		return now.Add(-time.Duration(u.Seconds) * time.Second), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, usagef("Invalid time %q", s)
+}
+
+func history(e *env, args []string) error {
+	fs := newFlags("history")
+	itemId := fs.String("item", "", "item id")
+	from := fs.String("from", "1h", "start time")
+	to := fs.String("to", "now", "end time")
+	limit := fs.Int("limit", 0, "maximum number of values, 0 for no limit")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *itemId == "" || fs.NArg() != 0 {
+		return usagef("Expected --item")
+	}
+	now := time.Now()
+	fromTime, err := parseTime(*from, now)
+	if err != nil {
+		return err
+	}
+	toTime, err := parseTime(*to, now)
+	if err != nil {
+		return err
+	}
+	api, err := e.API()
+	if err != nil {
+		return err
+	}
+
+	items, err := api.ItemsGet(zabbix.Params{"itemids": *itemId, "output": []string{"itemid", "key_", "value_type"}})
+	if err != nil {
+		return err
+	}
+	if len(items) != 1 {
+		return notFound("Item", *itemId)
+	}
+	params := zabbix.Params{
+		"time_from": fromTime.Unix(),
+		"time_till": toTime.Unix(),
+		"sortfield": "clock",
+		"sortorder": "ASC",
+	}
+	if *limit > 0 {
+		params["limit"] = *limit
+	}
+	h, err := api.HistoryGetByItem(&items[0], params)
+	if err != nil {
+		return err
+	}
+
+	t := &table{columns: []string{"clock", "value"}}
+	add := func(clock time.Time, value interface{}) {
+		t.add(clock.Format(time.RFC3339Nano), fmt.Sprint(value))
+	}
+	switch {
+	case h.Float != nil:
+		t.objects = h.Float
+		for _, v := range h.Float {
+			add(v.Time(), v.Value)
+		}
+	case h.Uint != nil:
+		t.objects = h.Uint
+		for _, v := range h.Uint {
+			add(v.Time(), v.Value)
+		}
+	case h.Text != nil:
+		t.objects = h.Text
+		for _, v := range h.Text {
+			add(v.Time(), v.Value)
+		}
+	case h.Log != nil:
+		t.objects = h.Log
+		for _, v := range h.Log {
+			add(v.Time(), v.Value)
+		}
+	default:
+		t.objects = []interface{}{}
+	}
+	return e.print(t)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Connection settings.
+type profile struct {
+	URL      string `json:"url"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// Returns profile with non-empty fields of o replacing fields of p.
+func (p profile) override(o profile) profile {
+	if o.URL != "" {
+		p.URL = o.URL
+	}
+	if o.User != "" {
+		p.User = o.User
+	}
+	if o.Password != "" {
+		p.Password = o.Password
+	}
+	return p
+}
+
+// Profiles file.
+type config struct {
+	Default  string             `json:"default"`
+	Profiles map[string]profile `json:"profiles"`
+}
+
+// Returns $ZBX_CONFIG or ~/.zbx.json.
+func defaultConfigFile() string {
+	if f := os.Getenv("ZBX_CONFIG"); f != "" {
+		return f
+	}
+	return filepath.Join(os.Getenv("HOME"), ".zbx.json")
+}
+
+// Reads profiles file, missing file is the same as empty one.
+func loadConfig(name string) (c *config, err error) {
+	c = new(config)
+	b, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return
+}
+
+// Returns profile by name, or default profile for empty name. Empty profile is returned if there is no default one.
+func (c *config) Profile(name string) (p profile, err error) {
+	if name == "" {
+		name = c.Default
+		if name == "" {
+			return
+		}
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		err = usagef("Unknown profile %q", name)
+	}
+	return
+}
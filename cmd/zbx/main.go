@@ -0,0 +1,228 @@
+// Command zbx is command-line client for Zabbix API.
+//
+// Usage:
+//
+//	zbx [global flags] command [subcommand] [flags] [args]
+//
+// Commands:
+//
+//	host list [--group NAME] [--search TEXT]
+//	host get HOST
+//	host create --group NAME[,NAME] [--name NAME] [--ip IP | --dns DNS] [--port PORT] [--template NAME[,NAME]] [--proxy NAME] HOST
+//	host delete HOST...
+//	item list --host HOST [--key PATTERN]
+//	trigger list [--host HOST] [--problem]
+//	template export [--format xml|json|yaml] [--file FILE] TEMPLATE...
+//	template import [--delete-missing] FILE
+//	script run --host HOST SCRIPT
+//	history --item ID [--from TIME] [--to TIME] [--limit N]
+//
+// Flags go before arguments. Global flags are:
+//
+//	--config FILE    profiles file, $ZBX_CONFIG or ~/.zbx.json by default
+//	--profile NAME   profile, default one from profiles file by default
+//	--url, --user, --password  override profile, also set by $ZBX_URL, $ZBX_USER and $ZBX_PASSWORD
+//	--output FORMAT  table (default), json, yaml or csv
+//
+// Profiles file is JSON like:
+//
+//	{"default": "prod", "profiles": {"prod": {"url": "http://host/api_jsonrpc.php", "user": "Admin", "password": "zabbix"}}}
+//
+// Exit codes:
+//
+//	0  success
+//	1  other errors
+//	2  invalid usage
+//	3  Zabbix API error not listed below
+//	4  invalid params (-32602)
+//	5  authentication error
+//	6  object not found
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/wOvAN/zabbix"
+)
+
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitAPI      = 3
+	exitParams   = 4
+	exitAuth     = 5
+	exitNotFound = 6
+)
+
+// Error for invalid command line.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func usagef(format string, args ...interface{}) error {
+	return usageError(fmt.Sprintf(format, args...))
+}
+
+// Returns exit code for error.
+func exitCode(err error) int {
+	switch e := err.(type) {
+	case nil:
+		return exitOK
+	case usageError:
+		return exitUsage
+	case *notFoundError:
+		return exitNotFound
+	case *zabbix.ExpectedOneResult:
+		if *e == 0 {
+			return exitNotFound
+		}
+		return exitError
+	case *zabbix.Error:
+		text := strings.ToLower(e.Message + " " + e.Data)
+		for _, s := range []string{"not authorised", "not authorized", "session terminated", "incorrect user name or password",
+			"login name or password is incorrect"} {
+			if strings.Contains(text, s) {
+				return exitAuth
+			}
+		}
+		if e.Code == -32602 {
+			return exitParams
+		}
+		return exitAPI
+	}
+	return exitError
+}
+
+// State shared by commands.
+type env struct {
+	profile profile
+	format  string
+	stdout  io.Writer
+	api     *zabbix.API
+}
+
+// Returns logged in API, logging in on first call.
+func (e *env) API() (*zabbix.API, error) {
+	if e.api != nil {
+		return e.api, nil
+	}
+	if e.profile.URL == "" {
+		return nil, usagef("Zabbix API URL is not set, use --url, $ZBX_URL or profile")
+	}
+	api := zabbix.NewAPI(e.profile.URL)
+	if e.profile.User != "" {
+		if _, err := api.Login(e.profile.User, e.profile.Password); err != nil {
+			return nil, err
+		}
+	}
+	e.api = api
+	return api, nil
+}
+
+// Writes result in selected format.
+func (e *env) print(t *table) error {
+	return writeOutput(e.stdout, e.format, t)
+}
+
+type command struct {
+	usage string
+	run   func(e *env, args []string) error
+}
+
+// Commands by name, like "host list".
+var commands = map[string]command{
+	"host list":       {"[--group NAME] [--search TEXT]", hostList},
+	"host get":        {"HOST", hostGet},
+	"host create":     {"--group NAME[,NAME] [--name NAME] [--ip IP | --dns DNS] [--port PORT] [--template NAME[,NAME]] [--proxy NAME] HOST", hostCreate},
+	"host delete":     {"HOST...", hostDelete},
+	"item list":       {"--host HOST [--key PATTERN]", itemList},
+	"trigger list":    {"[--host HOST] [--problem]", triggerList},
+	"template export": {"[--format xml|json|yaml] [--file FILE] TEMPLATE...", templateExport},
+	"template import": {"[--delete-missing] FILE", templateImport},
+	"script run":      {"--host HOST SCRIPT", scriptRun},
+	"history":         {"--item ID [--from TIME] [--to TIME] [--limit N]", history},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: zbx [--config FILE] [--profile NAME] [--url URL] [--user USER] [--password PASSWORD] [--output table|json|yaml|csv] command")
+	fmt.Fprintln(w, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s %s\n", name, commands[name].usage)
+	}
+}
+
+// Runs command line without program name.
+func run(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("zbx", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(stderr) }
+	configFile := fs.String("config", defaultConfigFile(), "profiles file")
+	profileName := fs.String("profile", "", "profile name")
+	url := fs.String("url", "", "Zabbix API URL")
+	user := fs.String("user", "", "user name")
+	password := fs.String("password", "", "password")
+	format := fs.String("output", "table", "output format: table, json, yaml or csv")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if !validFormat(*format) {
+		return usagef("Unknown output format %q", *format)
+	}
+
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	p, err := config.Profile(*profileName)
+	if err != nil {
+		return err
+	}
+	p = p.override(profile{URL: os.Getenv("ZBX_URL"), User: os.Getenv("ZBX_USER"), Password: os.Getenv("ZBX_PASSWORD")})
+	p = p.override(profile{URL: *url, User: *user, Password: *password})
+
+	args = fs.Args()
+	if len(args) == 0 {
+		usage(stderr)
+		return usagef("No command")
+	}
+	name := args[0]
+	c, ok := commands[name]
+	if !ok && len(args) > 1 {
+		name += " " + args[1]
+		c, ok = commands[name]
+	}
+	if !ok {
+		usage(stderr)
+		return usagef("Unknown command %q", strings.Join(args, " "))
+	}
+	e := &env{profile: p, format: *format, stdout: stdout}
+	err = c.run(e, args[len(strings.Fields(name)):])
+	if _, ok := err.(usageError); ok {
+		fmt.Fprintf(stderr, "Usage: zbx %s %s\n", name, c.usage)
+	}
+	if e.api != nil && e.profile.User != "" {
+		e.api.Logout()
+	}
+	return err
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zbx: %s\n", err)
+	}
+	os.Exit(exitCode(err))
+}
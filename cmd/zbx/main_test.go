@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wOvAN/zabbix"
+)
+
+func TestExitCode(t *testing.T) {
+	zero, one := zabbix.ExpectedOneResult(0), zabbix.ExpectedOneResult(2)
+	for _, c := range []struct {
+		err  error
+		code int
+	}{
+		{nil, exitOK},
+		{usagef("bad"), exitUsage},
+		{notFound("Host", "web"), exitNotFound},
+		{&zero, exitNotFound},
+		{&one, exitError},
+		{&zabbix.Error{Code: -32602, Message: "Invalid params.", Data: "Not authorised."}, exitAuth},
+		{&zabbix.Error{Code: -32500, Message: "Application error.", Data: "Login name or password is incorrect."}, exitAuth},
+		{&zabbix.Error{Code: -32602, Message: "Invalid params.", Data: "Host with the same name already exists."}, exitParams},
+		{&zabbix.Error{Code: -32500, Message: "Application error.", Data: "No permissions."}, exitAPI},
+		{os.ErrNotExist, exitError},
+	} {
+		if code := exitCode(c.err); code != c.code {
+			t.Errorf("exitCode(%v) = %d, expected %d", c.err, code, c.code)
+		}
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	hosts := zabbix.Hosts{{HostId: "10105", Host: "web", Name: "Web, main", Status: zabbix.Monitored}}
+	tbl := &table{columns: []string{"hostid", "host", "name"}, objects: hosts}
+	tbl.add("10105", "web", "Web, main")
+
+	for format, expected := range map[string]string{
+		"table": "HOSTID  HOST  NAME\n10105   web   Web, main\n",
+		"csv":   "hostid,host,name\n10105,web,\"Web, main\"\n",
+	} {
+		var b bytes.Buffer
+		if err := writeOutput(&b, format, tbl); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != expected {
+			t.Errorf("Bad %s output:\n%s", format, b.String())
+		}
+	}
+
+	var b bytes.Buffer
+	if err := writeOutput(&b, "json", tbl); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b.Bytes(), []byte("[\n  {\n    \"hostid\": \"10105\",")) {
+		t.Errorf("Bad json output:\n%s", b.String())
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	value := map[string]interface{}{
+		"host":   "web",
+		"port":   "10050",
+		"status": 0,
+		"empty":  []string{},
+		"macros": []map[string]interface{}{{"macro": "{$A}", "value": "a: b"}, {"macro": "{$B}", "value": "line\nnext"}},
+		"groups": []string{"Linux servers", "true"},
+		"none":   nil,
+	}
+	expected := `empty: []
+groups:
+  - Linux servers
+  - "true"
+host: web
+macros:
+  - macro: "{$A}"
+    value: "a: b"
+  - macro: "{$B}"
+    value: "line\nnext"
+none: null
+port: "10050"
+status: 0
+`
+	var b bytes.Buffer
+	if err := writeYAML(&b, value); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != expected {
+		t.Errorf("Bad yaml:\n%s", b.String())
+	}
+
+	b.Reset()
+	writeYAML(&b, []string{})
+	if b.String() != "[]\n" {
+		t.Errorf("Bad empty yaml %q", b.String())
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2016, 10, 19, 15, 4, 5, 0, time.UTC)
+	for s, expected := range map[string]time.Time{
+		"now":                  now,
+		"1h":                   now.Add(-time.Hour),
+		"-2d":                  now.Add(-48 * time.Hour),
+		"1476889445":           time.Unix(1476889445, 0),
+		"2016-10-19T10:00:00Z": time.Date(2016, 10, 19, 10, 0, 0, 0, time.UTC),
+		"2016-10-19":           time.Date(2016, 10, 19, 0, 0, 0, 0, time.Local),
+	} {
+		res, err := parseTime(s, now)
+		if err != nil || !res.Equal(expected) {
+			t.Errorf("parseTime(%q) = %v, %v", s, res, err)
+		}
+	}
+	if _, err := parseTime("yesterday", now); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error, got %v", err)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "zbx.json")
+	data := `{"default": "prod", "profiles": {"prod": {"url": "http://prod/api_jsonrpc.php", "user": "Admin", "password": "secret"},
+		"test": {"url": "http://test/api_jsonrpc.php"}}}`
+	if err = ioutil.WriteFile(name, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadConfig(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := c.Profile("")
+	if err != nil || p.URL != "http://prod/api_jsonrpc.php" || p.Password != "secret" {
+		t.Errorf("Bad default profile %#v %v", p, err)
+	}
+	p, err = c.Profile("test")
+	if err != nil || p.User != "" {
+		t.Errorf("Bad test profile %#v %v", p, err)
+	}
+	if p = p.override(profile{User: "guest"}); p.URL != "http://test/api_jsonrpc.php" || p.User != "guest" {
+		t.Errorf("Bad overridden profile %#v", p)
+	}
+	if _, err = c.Profile("missing"); exitCode(err) != exitUsage {
+		t.Errorf("Expected usage error, got %v", err)
+	}
+
+	if c, err = loadConfig(filepath.Join(dir, "missing.json")); err != nil || len(c.Profiles) != 0 {
+		t.Errorf("Bad missing config %#v %v", c, err)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"--config", "/nonexistent", "--url", "http://127.0.0.1:1/api_jsonrpc.php"}
+	for _, a := range [][]string{
+		{},
+		{"host", "frobnicate"},
+		{"host", "create", "web"},
+		{"--output", "xml", "host", "list"},
+		{"item", "list"},
+		{"history", "--item", "1", "--from", "yesterday"},
+	} {
+		err := run(append(append([]string{}, args...), a...), &stdout, &stderr)
+		if exitCode(err) != exitUsage {
+			t.Errorf("%v: expected usage error, got %v", a, err)
+		}
+	}
+}
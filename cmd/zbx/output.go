@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Command result: columns and rows for table and csv output, objects for json and yaml.
+type table struct {
+	columns []string
+	rows    [][]string
+	objects interface{}
+}
+
+func (t *table) add(row ...string) {
+	t.rows = append(t.rows, row)
+}
+
+func validFormat(format string) bool {
+	switch format {
+	case "table", "json", "yaml", "csv":
+		return true
+	}
+	return false
+}
+
+func writeOutput(w io.Writer, format string, t *table) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(t.columns, "\t")))
+		for _, row := range t.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(t.columns)
+		cw.WriteAll(t.rows)
+		return cw.Error()
+	case "json":
+		b, err := json.MarshalIndent(t.objects, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b, '\n'))
+		return err
+	case "yaml":
+		return writeYAML(w, t.objects)
+	}
+	return usagef("Unknown output format %q", format)
+}
+
+// Writes value as YAML block document. Value is converted to JSON first, so json tags are used
+// and map keys are sorted.
+func writeYAML(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var data interface{}
+	if err = d.Decode(&data); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	switch data := data.(type) {
+	case map[string]interface{}:
+		if len(data) > 0 {
+			writeYAMLMap(&buf, data, 0, false)
+			break
+		}
+		buf.WriteString("{}\n")
+	case []interface{}:
+		if len(data) > 0 {
+			writeYAMLList(&buf, data, 0)
+			break
+		}
+		buf.WriteString("[]\n")
+	default:
+		buf.WriteString(yamlScalar(data))
+		buf.WriteByte('\n')
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// Writes value after "key:" or "-": scalars and empty collections on the same line, others on next lines.
+func writeYAMLValue(b *bytes.Buffer, v interface{}, indent int) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+		b.WriteByte('\n')
+		writeYAMLMap(b, v, indent, false)
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		b.WriteByte('\n')
+		writeYAMLList(b, v, indent)
+	default:
+		b.WriteByte(' ')
+		b.WriteString(yamlScalar(v))
+		b.WriteByte('\n')
+	}
+}
+
+// Writes map entries with sorted keys, first key is not indented if it follows "- ".
+func writeYAMLMap(b *bytes.Buffer, m map[string]interface{}, indent int, inline bool) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i > 0 || !inline {
+			b.WriteString(strings.Repeat(" ", indent))
+		}
+		b.WriteString(yamlScalar(k))
+		b.WriteByte(':')
+		writeYAMLValue(b, m[k], indent+2)
+	}
+}
+
+func writeYAMLList(b *bytes.Buffer, l []interface{}, indent int) {
+	for _, v := range l {
+		b.WriteString(strings.Repeat(" ", indent))
+		b.WriteByte('-')
+		if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+			b.WriteByte(' ')
+			writeYAMLMap(b, m, indent+2, true)
+			continue
+		}
+		writeYAMLValue(b, v, indent+2)
+	}
+}
+
+var (
+	// plain scalars which would be read as something other than string
+	yamlSpecialRE = regexp.MustCompile(`^(?i:true|false|yes|no|y|n|on|off|null|~|[-+.]?[0-9].*|\.inf|\.nan)$`)
+	yamlPlainRE   = regexp.MustCompile(`^[^-?:,\[\]{}#&*!|>'"%@` + "`" + `\s][^\x00-\x1f\x7f]*$`)
+)
+
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		if yamlPlainRE.MatchString(v) && !yamlSpecialRE.MatchString(v) && !strings.HasSuffix(v, " ") &&
+			!strings.Contains(v, ": ") && !strings.Contains(v, " #") && !strings.HasSuffix(v, ":") {
+			return v
+		}
+		return strconv.Quote(v)
+	}
+	return strconv.Quote(fmt.Sprint(v))
+}
@@ -0,0 +1,186 @@
+// configuration import and export
+
+package zabbix
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Format of exported and imported configuration.
+type ConfigurationFormat string
+
+const (
+	ConfigurationXML  ConfigurationFormat = "xml"
+	ConfigurationJSON ConfigurationFormat = "json"
+	ConfigurationYAML ConfigurationFormat = "yaml" // Zabbix 5.2+
+)
+
+// Returns format by file extension: .xml, .json, .yaml or .yml.
+func ConfigurationFormatFromFile(name string) (ConfigurationFormat, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".xml":
+		return ConfigurationXML, nil
+	case ".json":
+		return ConfigurationJSON, nil
+	case ".yaml", ".yml":
+		return ConfigurationYAML, nil
+	}
+	return "", fmt.Errorf("Unknown configuration format of %s", name)
+}
+
+// Objects to export, by ids.
+// https://www.zabbix.com/documentation/4.0/manual/api/reference/configuration/export
+type ExportOptions struct {
+	Groups     []string `json:"groups,omitempty"`
+	Hosts      []string `json:"hosts,omitempty"`
+	Images     []string `json:"images,omitempty"`
+	Maps       []string `json:"maps,omitempty"`
+	MediaTypes []string `json:"mediaTypes,omitempty"` // Zabbix 5.0+
+	Screens    []string `json:"screens,omitempty"`
+	Templates  []string `json:"templates,omitempty"`
+	ValueMaps  []string `json:"valueMaps,omitempty"`
+}
+
+// Wrapper for configuration.export: https://www.zabbix.com/documentation/4.0/manual/api/reference/configuration/export
+func (api *API) ConfigurationExport(format ConfigurationFormat, options ExportOptions) (res string, err error) {
+	response, err := api.CallWithError("configuration.export", Params{"format": format, "options": options})
+	if err != nil {
+		return
+	}
+
+	res, ok := response.Result.(string)
+	if !ok {
+		err = fmt.Errorf("Unexpected configuration.export result %T", response.Result)
+	}
+	return
+}
+
+// Exports templates with given ids.
+func (api *API) TemplatesExport(format ConfigurationFormat, ids []string) (string, error) {
+	return api.ConfigurationExport(format, ExportOptions{Templates: ids})
+}
+
+// Import rule for one kind of objects. Not all kinds support all options, false options are not sent.
+type ImportRule struct {
+	CreateMissing  bool `json:"createMissing,omitempty"`
+	UpdateExisting bool `json:"updateExisting,omitempty"`
+	DeleteMissing  bool `json:"deleteMissing,omitempty"`
+}
+
+// Rules of configuration.import, nil kinds are not sent.
+// https://www.zabbix.com/documentation/4.0/manual/api/reference/configuration/import
+type ImportRules struct {
+	Applications       *ImportRule `json:"applications,omitempty"`
+	DiscoveryRules     *ImportRule `json:"discoveryRules,omitempty"`
+	Graphs             *ImportRule `json:"graphs,omitempty"`
+	Groups             *ImportRule `json:"groups,omitempty"`
+	HostGroups         *ImportRule `json:"host_groups,omitempty"` // Zabbix 6.2+, replaces Groups
+	Hosts              *ImportRule `json:"hosts,omitempty"`
+	HttpTests          *ImportRule `json:"httptests,omitempty"`
+	Images             *ImportRule `json:"images,omitempty"`
+	Items              *ImportRule `json:"items,omitempty"`
+	Maps               *ImportRule `json:"maps,omitempty"`
+	MediaTypes         *ImportRule `json:"mediaTypes,omitempty"` // Zabbix 5.0+
+	Screens            *ImportRule `json:"screens,omitempty"`
+	TemplateDashboards *ImportRule `json:"templateDashboards,omitempty"` // Zabbix 5.2+, replaces TemplateScreens
+	TemplateGroups     *ImportRule `json:"template_groups,omitempty"`    // Zabbix 6.2+
+	TemplateLinkage    *ImportRule `json:"templateLinkage,omitempty"`
+	Templates          *ImportRule `json:"templates,omitempty"`
+	TemplateScreens    *ImportRule `json:"templateScreens,omitempty"`
+	Triggers           *ImportRule `json:"triggers,omitempty"`
+	ValueMaps          *ImportRule `json:"valueMaps,omitempty"`
+}
+
+// Returns rules for all kinds supported by Zabbix 4.0, creating missing and updating existing objects.
+// If deleteMissing is set, objects missing in import are deleted for kinds which support it.
+func FullImportRules(deleteMissing bool) *ImportRules {
+	// each kind gets own rule, so callers may change them separately
+	create := func() *ImportRule { return &ImportRule{CreateMissing: true} }
+	update := func() *ImportRule { return &ImportRule{CreateMissing: true, UpdateExisting: true} }
+	child := func() *ImportRule {
+		return &ImportRule{CreateMissing: true, UpdateExisting: true, DeleteMissing: deleteMissing}
+	}
+	return &ImportRules{
+		Applications:    &ImportRule{CreateMissing: true, DeleteMissing: deleteMissing},
+		DiscoveryRules:  child(),
+		Graphs:          child(),
+		Groups:          create(),
+		Hosts:           update(),
+		HttpTests:       child(),
+		Images:          update(),
+		Items:           child(),
+		Maps:            update(),
+		Screens:         update(),
+		TemplateLinkage: create(),
+		Templates:       update(),
+		TemplateScreens: child(),
+		Triggers:        child(),
+		ValueMaps:       update(),
+	}
+}
+
+// Wrapper for configuration.import: https://www.zabbix.com/documentation/4.0/manual/api/reference/configuration/import
+func (api *API) ConfigurationImport(format ConfigurationFormat, source string, rules *ImportRules) (err error) {
+	response, err := api.CallWithError("configuration.import", Params{"format": format, "rules": rules, "source": source})
+	if err != nil {
+		return
+	}
+
+	if ok, _ := response.Result.(bool); !ok {
+		err = fmt.Errorf("Unexpected configuration.import result %v", response.Result)
+	}
+	return
+}
+
+// Imports file, format is detected by extension.
+func (api *API) ConfigurationImportFile(name string, rules *ImportRules) (err error) {
+	format, err := ConfigurationFormatFromFile(name)
+	if err != nil {
+		return
+	}
+	source, err := ioutil.ReadFile(name)
+	if err != nil {
+		return
+	}
+	return api.ConfigurationImport(format, string(source), rules)
+}
+
+// Changes which would be made by import, keyed by object kind like "templates".
+// Each kind has "added", "removed" and "updated" lists, updated objects have "before", "after" and nested changes.
+type ImportChanges map[string]interface{}
+
+// Returns true if import would not change anything.
+func (c ImportChanges) Empty() bool {
+	return len(c) == 0
+}
+
+// Returns kinds of changed objects in no particular order.
+func (c ImportChanges) Kinds() (res []string) {
+	for kind := range c {
+		res = append(res, kind)
+	}
+	return
+}
+
+// Wrapper for configuration.importcompare (Zabbix 6.0+):
+// https://www.zabbix.com/documentation/6.0/manual/api/reference/configuration/importcompare
+func (api *API) ConfigurationImportCompare(format ConfigurationFormat, source string, rules *ImportRules) (res ImportChanges, err error) {
+	response, err := api.CallWithError("configuration.importcompare", Params{"format": format, "rules": rules, "source": source})
+	if err != nil {
+		return
+	}
+
+	switch result := response.Result.(type) {
+	case map[string]interface{}:
+		res = ImportChanges(result)
+	case []interface{}:
+		// empty object is encoded as empty array by PHP
+		res = ImportChanges{}
+	default:
+		err = fmt.Errorf("Unexpected configuration.importcompare result %T", response.Result)
+	}
+	return
+}
@@ -0,0 +1,63 @@
+package zabbix_test
+
+import (
+	. "."
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConfigurationRules(t *testing.T) {
+	for name, expected := range map[string]ConfigurationFormat{"a.xml": ConfigurationXML, "b.JSON": ConfigurationJSON, "c.yml": ConfigurationYAML} {
+		if f, err := ConfigurationFormatFromFile(name); err != nil || f != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", name, expected, f, err)
+		}
+	}
+	if _, err := ConfigurationFormatFromFile("template.txt"); err == nil {
+		t.Error("Expected error for unknown extension")
+	}
+
+	rules := FullImportRules(true)
+	rules.Hosts.UpdateExisting = false
+	b, err := json.Marshal(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	for _, expected := range []string{
+		`"applications":{"createMissing":true,"deleteMissing":true}`,
+		`"hosts":{"createMissing":true}`,
+		`"templates":{"createMissing":true,"updateExisting":true}`,
+		`"items":{"createMissing":true,"updateExisting":true,"deleteMissing":true}`,
+	} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("Expected %s in %s", expected, s)
+		}
+	}
+	if strings.Contains(s, "mediaTypes") {
+		t.Errorf("Unexpected rule for media types in %s", s)
+	}
+}
+
+func TestConfiguration(t *testing.T) {
+	api := getAPI(t)
+
+	group := CreateHostGroup(t)
+	defer DeleteHostGroup(group, t)
+
+	host := CreateHost(group, t)
+	defer DeleteHost(host, t)
+
+	source, err := api.ConfigurationExport(ConfigurationJSON, ExportOptions{Hosts: []string{host.HostId}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(source, host.Host) {
+		t.Errorf("Host %s is not exported: %s", host.Host, source)
+	}
+
+	err = api.ConfigurationImport(ConfigurationJSON, source, FullImportRules(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,157 @@
+package zabbix
+
+import (
+	"fmt"
+)
+
+// Creates item of DependentItem type which takes values from master item and extracts data with preprocessing steps.
+// HostId and MasterItemId are filled by ItemsCreateWithDependents.
+func NewDependentItem(key, name string, valueType ValueType, steps ...PreprocessingStep) Item {
+	return Item{
+		Key:           key,
+		Name:          name,
+		Type:          DependentItem,
+		ValueType:     valueType,
+		Delay:         "0",
+		Preprocessing: PreprocessingSteps(steps),
+	}
+}
+
+// Creates dependent item which extracts value from master item JSON with JSONPath expression.
+func NewJSONPathItem(key, name string, valueType ValueType, path string) Item {
+	return NewDependentItem(key, name, valueType, JSONPathStep(path))
+}
+
+// Returns items which master item is item with given Id.
+func (items Items) Dependents(masterItemId string) (res Items) {
+	for _, item := range items {
+		if item.MasterItemId == masterItemId {
+			res = append(res, item)
+		}
+	}
+	return
+}
+
+// Creates master item, then all dependent items on the same host.
+// Empty HostId, MasterItemId and Type of dependents are filled from master.
+// If dependent items can't be created, master item is deleted so nothing is left behind.
+func (api *API) ItemsCreateWithDependents(master *Item, dependents Items) (err error) {
+	masters := Items{*master}
+	err = api.ItemsCreate(masters)
+	if err != nil {
+		return
+	}
+	*master = masters[0]
+
+	for i := range dependents {
+		if dependents[i].HostId == "" {
+			dependents[i].HostId = master.HostId
+		}
+		dependents[i].Type = DependentItem
+		dependents[i].MasterItemId = master.ItemId
+	}
+	if len(dependents) == 0 {
+		return
+	}
+
+	err = api.ItemsCreate(dependents)
+	if err != nil {
+		// dependent items are deleted together with master
+		if e := api.ItemsDeleteByIds([]string{master.ItemId}); e != nil {
+			err = fmt.Errorf("%s (rollback failed: %s)", err, e)
+			return
+		}
+		master.ItemId = ""
+		for i := range dependents {
+			dependents[i].ItemId = ""
+			dependents[i].MasterItemId = ""
+		}
+	}
+	return
+}
+
+// Maximum depth of dependent items chain allowed by Zabbix.
+const maxItemDependencyLevel = 3
+
+// Creates items of one host in dependency order: masters are created before their dependents.
+// Dependent items refer to masters by key in masterKeys (dependent key -> master key).
+// Master items which already exist on server may be referred by MasterItemId directly.
+// Items of different hosts are rejected, since keys are unique only within host.
+// Chains deeper than 3 levels are rejected before creating anything, since Zabbix doesn't allow them;
+// depth of masters referred by MasterItemId is not checked.
+// On failure all items created by this call are deleted.
+func (api *API) ItemsCreateOrdered(items Items, masterKeys map[string]string) (err error) {
+	index := make(map[string]int, len(items))
+	for i, item := range items {
+		if item.HostId != items[0].HostId {
+			return fmt.Errorf("Items of different hosts %s and %s must be created separately", items[0].HostId, item.HostId)
+		}
+		index[item.Key] = i
+	}
+
+	// depth of item in dependency chain
+	depth := make([]int, len(items))
+	for i, item := range items {
+		seen := map[int]bool{i: true}
+		current := item
+		for {
+			key, ok := masterKeys[current.Key]
+			if !ok {
+				break
+			}
+			j, present := index[key]
+			if !present {
+				return fmt.Errorf("Master item %s for %s not found", key, current.Key)
+			}
+			if seen[j] {
+				return fmt.Errorf("Circular dependency for item %s", item.Key)
+			}
+			seen[j] = true
+			depth[i]++
+			current = items[j]
+		}
+		if depth[i] > maxItemDependencyLevel {
+			return fmt.Errorf("Dependency chain of item %s is deeper than %d levels", item.Key, maxItemDependencyLevel)
+		}
+	}
+
+	var created Items
+	rollback := func() {
+		if len(created) == 0 {
+			return
+		}
+		if e := api.ItemsDeleteByIds(created.Ids()); e != nil {
+			err = fmt.Errorf("%s (rollback failed: %s)", err, e)
+		}
+		for i := range items {
+			items[i].ItemId = ""
+		}
+	}
+
+	for level := 0; len(created) < len(items); level++ {
+		var batch Items
+		var positions []int
+		for i, item := range items {
+			if depth[i] != level {
+				continue
+			}
+			if key, ok := masterKeys[item.Key]; ok {
+				item.Type = DependentItem
+				item.MasterItemId = items[index[key]].ItemId
+			}
+			batch = append(batch, item)
+			positions = append(positions, i)
+		}
+
+		err = api.ItemsCreate(batch)
+		if err != nil {
+			rollback()
+			return
+		}
+		for j, i := range positions {
+			items[i] = batch[j]
+		}
+		created = append(created, batch...)
+	}
+	return
+}
@@ -0,0 +1,392 @@
+// object diff engine
+
+package zabbix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Difference of single field. Field is JSON name, nested fields are like "interfaces[0].port".
+// Old or New is nil if field is missing on that side.
+type FieldDiff struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+func (d FieldDiff) String() string {
+	return fmt.Sprintf("%s: %#v -> %#v", d.Field, d.Old, d.New)
+}
+
+// Fields not compared by DiffHosts and other functions, because they are assigned by server or change on their own.
+// Fields named like "...id" and "...ids" are also ignored: ids differ between servers and backups.
+// They are ignored in nested objects too, so value of triggers is skipped by DiffTriggers only,
+// since values of macros are compared.
+var DiffIgnoredFields = map[string]bool{
+	"error":      true,
+	"available":  true,
+	"lastchange": true,
+	"state":      true,
+	"flags":      true,
+}
+
+func diffIgnored(name string) bool {
+	return DiffIgnoredFields[name] || strings.HasSuffix(name, "id") || strings.HasSuffix(name, "ids")
+}
+
+// Compares values field by field.
+type differ struct {
+	ignore    func(name string) bool
+	skipEmpty bool // skip empty strings and nil interfaces in new value
+	deep      bool // compare slices, maps and nested structs
+	res       []FieldDiff
+}
+
+var timestampType = reflect.TypeOf(Timestamp{})
+
+func (d *differ) structs(prefix string, a, b reflect.Value) {
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.PkgPath != "" || name == "" || name == "-" || d.ignore(name) {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		d.value(name, a.Field(i), b.Field(i))
+	}
+}
+
+func (d *differ) value(path string, a, b reflect.Value) {
+	if b.Kind() == reflect.Interface && a.Kind() == reflect.Interface && !a.IsNil() && !b.IsNil() &&
+		a.Elem().Kind() == b.Elem().Kind() {
+		a, b = a.Elem(), b.Elem()
+	}
+	switch b.Kind() {
+	case reflect.Slice:
+		if d.deep {
+			d.slices(path, a, b)
+		}
+		return
+	case reflect.Map:
+		if d.deep {
+			d.maps(path, a, b)
+		}
+		return
+	case reflect.Ptr:
+		if !d.deep {
+			return
+		}
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.res = append(d.res, FieldDiff{path, diffValue(a), diffValue(b)})
+			}
+			return
+		}
+		d.value(path, a.Elem(), b.Elem())
+		return
+	case reflect.Struct:
+		if b.Type() != timestampType {
+			if d.deep {
+				d.structs(path, a, b)
+			}
+			return
+		}
+	case reflect.Interface:
+		if d.skipEmpty && b.IsNil() {
+			return
+		}
+	case reflect.String:
+		if d.skipEmpty && b.Len() == 0 {
+			return
+		}
+	}
+	if diffString(a) != diffString(b) {
+		d.res = append(d.res, FieldDiff{path, diffValue(a), diffValue(b)})
+	}
+}
+
+// Natural keys of slice elements, so their order doesn't matter. Other slices are compared by index.
+var diffSliceKeys = map[reflect.Type]func(v reflect.Value) string{
+	reflect.TypeOf(HostInterface{}): func(v reflect.Value) string {
+		i := v.Interface().(HostInterface)
+		return fmt.Sprintf("type=%d,main=%d", i.Type, i.Main)
+	},
+	reflect.TypeOf(HostMacro{}): func(v reflect.Value) string { return v.Interface().(HostMacro).Macro },
+	reflect.TypeOf(Template{}):  func(v reflect.Value) string { return v.Interface().(Template).Host },
+}
+
+func (d *differ) slices(path string, a, b reflect.Value) {
+	if key := diffSliceKeys[b.Type().Elem()]; key != nil {
+		d.keyedSlices(path, a, b, key)
+		return
+	}
+	n := a.Len()
+	if b.Len() > n {
+		n = b.Len()
+	}
+	for i := 0; i < n; i++ {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= a.Len():
+			d.res = append(d.res, FieldDiff{p, nil, diffValue(b.Index(i))})
+		case i >= b.Len():
+			d.res = append(d.res, FieldDiff{p, diffValue(a.Index(i)), nil})
+		default:
+			d.value(p, a.Index(i), b.Index(i))
+		}
+	}
+}
+
+// Compares elements with the same key, path of element is like "macros[{$PORT}]".
+// Elements with duplicate keys, like non-main interfaces of the same type, get "#2", "#3" and so on appended.
+func (d *differ) keyedSlices(path string, a, b reflect.Value, key func(v reflect.Value) string) {
+	byKey := func(s reflect.Value) map[string]reflect.Value {
+		res := make(map[string]reflect.Value, s.Len())
+		for i := 0; i < s.Len(); i++ {
+			k := key(s.Index(i))
+			for n := 2; res[k].IsValid(); n++ {
+				k = fmt.Sprintf("%s#%d", key(s.Index(i)), n)
+			}
+			res[k] = s.Index(i)
+		}
+		return res
+	}
+	as, bs := byKey(a), byKey(b)
+	keys := make([]string, 0, len(as)+len(bs))
+	for k := range as {
+		keys = append(keys, k)
+	}
+	for k := range bs {
+		if !as[k].IsValid() {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p := fmt.Sprintf("%s[%s]", path, k)
+		av, bv := as[k], bs[k]
+		switch {
+		case !av.IsValid():
+			d.res = append(d.res, FieldDiff{p, nil, diffValue(bv)})
+		case !bv.IsValid():
+			d.res = append(d.res, FieldDiff{p, diffValue(av), nil})
+		default:
+			d.value(p, av, bv)
+		}
+	}
+}
+
+func (d *differ) maps(path string, a, b reflect.Value) {
+	keys := make(map[string]reflect.Value)
+	for _, k := range append(a.MapKeys(), b.MapKeys()...) {
+		keys[fmt.Sprint(k.Interface())] = k
+	}
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := path + "." + name
+		av, bv := a.MapIndex(keys[name]), b.MapIndex(keys[name])
+		switch {
+		case !av.IsValid():
+			d.res = append(d.res, FieldDiff{p, nil, diffValue(bv)})
+		case !bv.IsValid():
+			d.res = append(d.res, FieldDiff{p, diffValue(av), nil})
+		default:
+			d.value(p, av, bv)
+		}
+	}
+}
+
+func diffValue(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == timestampType {
+		t := v.Interface().(Timestamp)
+		return t.String()
+	}
+	return v.Interface()
+}
+
+func diffString(v reflect.Value) string {
+	if value := diffValue(v); value != nil {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+// Returns differences of scalar fields of two structs of the same type, used by reconciler.
+// Empty strings and nil interfaces in desired are skipped if skipEmpty is set.
+func diffFields(current, desired interface{}, ignore map[string]bool, skipEmpty bool) []FieldDiff {
+	d := &differ{ignore: func(name string) bool { return ignore[name] }, skipEmpty: skipEmpty}
+	d.structs("", reflect.Indirect(reflect.ValueOf(current)), reflect.Indirect(reflect.ValueOf(desired)))
+	return d.res
+}
+
+// Difference of objects with the same natural key. Action is create if object exists only in new set,
+// delete if only in old one, and update if fields differ.
+type ObjectDiff struct {
+	Action ChangeAction `json:"action"`
+	Kind   string       `json:"kind"`
+	Key    string       `json:"key"`
+	Fields []FieldDiff  `json:"fields,omitempty"`
+}
+
+type ObjectDiffs []ObjectDiff
+
+// Returns true if sets are equal.
+func (diffs ObjectDiffs) Empty() bool {
+	return len(diffs) == 0
+}
+
+// Returns text diff: "+" for created, "-" for deleted and "~" for updated objects, changed fields indented below.
+func (diffs ObjectDiffs) String() string {
+	var b bytes.Buffer
+	for _, d := range diffs {
+		writeChange(&b, d.Action, d.Kind, d.Key, d.Fields)
+	}
+	return b.String()
+}
+
+// Returns indented JSON.
+func (diffs ObjectDiffs) JSON() ([]byte, error) {
+	if diffs == nil {
+		diffs = ObjectDiffs{}
+	}
+	return json.MarshalIndent(diffs, "", "  ")
+}
+
+var changeSigns = map[ChangeAction]string{ChangeCreate: "+", ChangeUpdate: "~", ChangeDelete: "-"}
+
+func writeChange(b *bytes.Buffer, action ChangeAction, kind, key string, fields []FieldDiff) {
+	fmt.Fprintf(b, "%s %s %s\n", changeSigns[action], kind, key)
+	for _, f := range fields {
+		b.WriteString("    ")
+		b.WriteString(f.String())
+		b.WriteByte('\n')
+	}
+}
+
+// Compares two slices of objects by natural key, returning diffs sorted by key.
+// Fields in skip are not compared, for example nested objects compared separately; extra compares them if needed.
+func diffSets(kind, prefix string, old, new interface{}, key func(v reflect.Value) string,
+	skip map[string]bool, extra func(o, n reflect.Value) []FieldDiff) (res ObjectDiffs) {
+	ov, nv := reflect.ValueOf(old), reflect.ValueOf(new)
+	olds := make(map[string]reflect.Value, ov.Len())
+	news := make(map[string]reflect.Value, nv.Len())
+	var keys []string
+	for i := 0; i < ov.Len(); i++ {
+		k := key(ov.Index(i))
+		olds[k] = ov.Index(i)
+		keys = append(keys, k)
+	}
+	for i := 0; i < nv.Len(); i++ {
+		k := key(nv.Index(i))
+		news[k] = nv.Index(i)
+		if _, present := olds[k]; !present {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	ignore := func(name string) bool { return skip[name] || diffIgnored(name) }
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		o, oPresent := olds[k]
+		n, nPresent := news[k]
+		switch {
+		case !oPresent:
+			res = append(res, ObjectDiff{Action: ChangeCreate, Kind: kind, Key: prefix + k})
+		case !nPresent:
+			res = append(res, ObjectDiff{Action: ChangeDelete, Kind: kind, Key: prefix + k})
+		default:
+			d := &differ{ignore: ignore, deep: true}
+			d.structs("", o, n)
+			if extra != nil {
+				d.res = append(d.res, extra(o, n)...)
+			}
+			if len(d.res) > 0 {
+				res = append(res, ObjectDiff{Action: ChangeUpdate, Kind: kind, Key: prefix + k, Fields: d.res})
+			}
+		}
+	}
+	return
+}
+
+// Compares hosts by Host.
+func DiffHosts(old, new Hosts) ObjectDiffs {
+	return diffSets("host", "", old, new, func(v reflect.Value) string { return v.Interface().(Host).Host }, nil, nil)
+}
+
+// Compares items of one host or template by normalized Key, so keys differing only in formatting are equal.
+func DiffItems(old, new Items) ObjectDiffs {
+	return diffItems("", old, new)
+}
+
+func diffItems(prefix string, old, new Items) ObjectDiffs {
+	return diffSets("item", prefix, old, new, func(v reflect.Value) string { return NormalizeItemKey(v.Interface().(Item).Key) },
+		map[string]bool{"key_": true}, nil)
+}
+
+// Compares triggers of one host or template by Description, expressions and recovery expressions
+// are compared after normalization.
+// Expressions should be expanded,
+// see TriggersGetExpanded, since function ids differ between servers.
+func DiffTriggers(old, new Triggers) ObjectDiffs {
+	return diffTriggers("", old, new)
+}
+
+func diffTriggers(prefix string, old, new Triggers) ObjectDiffs {
+	return diffSets("trigger", prefix, old, new, func(v reflect.Value) string { return v.Interface().(Trigger).Description },
+		map[string]bool{"expression": true, "recovery_expression": true, "value": true}, diffExpressions)
+}
+
+func diffExpressions(o, n reflect.Value) (res []FieldDiff) {
+	old, new := o.Interface().(Trigger), n.Interface().(Trigger)
+	if normalizeExpression(old.Expression) != normalizeExpression(new.Expression) {
+		res = append(res, FieldDiff{"expression", old.Expression, new.Expression})
+	}
+	if normalizeExpression(old.Recovery_expression) != normalizeExpression(new.Recovery_expression) {
+		res = append(res, FieldDiff{"recovery_expression", old.Recovery_expression, new.Recovery_expression})
+	}
+	return
+}
+
+// Compares templates by Host. Items and triggers of templates present in both sets are compared
+// by their natural keys, with keys prefixed by template like "Template OS Linux:agent.ping".
+func DiffTemplates(old, new Templates) (res ObjectDiffs) {
+	res = diffSets("template", "", old, new, func(v reflect.Value) string { return v.Interface().(Template).Host },
+		map[string]bool{"items": true, "triggers": true}, nil)
+
+	olds := make(map[string]Template, len(old))
+	for _, t := range old {
+		olds[t.Host] = t
+	}
+	for _, n := range new {
+		o, present := olds[n.Host]
+		if !present {
+			continue
+		}
+		res = append(res, diffItems(n.Host+":", o.Items, n.Items)...)
+		res = append(res, diffTriggers(n.Host+":", o.Triggers, n.Triggers)...)
+	}
+	return
+}
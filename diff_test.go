@@ -0,0 +1,128 @@
+package zabbix_test
+
+import (
+	. "."
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiffHosts(t *testing.T) {
+	old := Hosts{
+		{HostId: "1", Host: "a", Name: "A", Status: Monitored, Error: "timeout",
+			Interfaces: HostInterfaces{{InterfaceId: "4", Type: SNMP, Main: 1, Port: "161"}, {InterfaceId: "5", Type: Agent, Main: 1, Port: "10050"}},
+			Macros:     HostMacros{{Macro: "{$A}", Value: "1"}, {Macro: "{$B}", Value: "2"}}},
+		{HostId: "2", Host: "b"},
+	}
+	new := Hosts{
+		{HostId: "11", Host: "a", Name: "A2", Status: Monitored,
+			Interfaces: HostInterfaces{{InterfaceId: "15", Type: Agent, Main: 1, Port: "10051"}, {InterfaceId: "14", Type: SNMP, Main: 1, Port: "161"}},
+			Macros:     HostMacros{{Macro: "{$B}", Value: "2"}, {Macro: "{$A}", Value: "1"}}},
+		{HostId: "12", Host: "c"},
+	}
+
+	diffs := DiffHosts(old, new)
+	if len(diffs) != 3 {
+		t.Fatalf("Bad diffs %#v", diffs)
+	}
+	if diffs[0].Action != ChangeUpdate || diffs[0].Key != "a" || len(diffs[0].Fields) != 2 {
+		t.Fatalf("Bad update %#v", diffs[0])
+	}
+	if f := diffs[0].Fields[0]; f.Field != "name" || f.Old != "A" || f.New != "A2" {
+		t.Errorf("Bad field %#v", f)
+	}
+	if f := diffs[0].Fields[1]; f.Field != "interfaces[type=1,main=1].port" || f.Old != "10050" {
+		t.Errorf("Bad field %#v", f)
+	}
+	if diffs[1].Action != ChangeDelete || diffs[1].Key != "b" || diffs[2].Action != ChangeCreate || diffs[2].Key != "c" {
+		t.Errorf("Bad diffs %#v", diffs[1:])
+	}
+
+	text := diffs.String()
+	expected := "~ host a\n    name: \"A\" -> \"A2\"\n"
+	if !strings.HasPrefix(text, expected) || !strings.Contains(text, "- host b\n+ host c\n") {
+		t.Errorf("Bad text:\n%s", text)
+	}
+
+	b, err := diffs.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]interface{}
+	if err = json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 3 || decoded[0]["key"] != "a" || decoded[2]["fields"] != nil {
+		t.Errorf("Bad JSON %s", b)
+	}
+
+	if diffs = DiffHosts(old, old); !diffs.Empty() {
+		t.Errorf("Expected no diffs, got %s", diffs)
+	}
+	if b, _ = diffs.JSON(); string(b) != "[]" {
+		t.Errorf("Bad empty JSON %s", b)
+	}
+}
+
+func TestDiffKeyedSlices(t *testing.T) {
+	old := Hosts{{Host: "a",
+		Interfaces:      HostInterfaces{{Type: Agent, Main: 1, Port: "10050"}, {Type: Agent, Main: 0, Port: "10051"}},
+		Macros:          HostMacros{{Macro: "{$PORT}", Value: "80"}, {Macro: "{$USER}", Value: "root"}},
+		ParentTemplates: Templates{{TemplateId: "1", Host: "Template A"}, {TemplateId: "2", Host: "Template B"}},
+	}}
+	new := Hosts{{Host: "a",
+		Interfaces:      HostInterfaces{{Type: Agent, Main: 0, Port: "10052"}, {Type: Agent, Main: 1, Port: "10050"}},
+		Macros:          HostMacros{{Macro: "{$USER}", Value: "root"}, {Macro: "{$PORT}", Value: "8080"}},
+		ParentTemplates: Templates{{TemplateId: "3", Host: "Template C"}, {TemplateId: "1", Host: "Template A"}},
+	}}
+	diffs := DiffHosts(old, new)
+	expected := `~ host a
+    interfaces[type=1,main=0].port: "10051" -> "10052"
+    parentTemplates[Template B]: zabbix.Template{TemplateId:"2", Host:"Template B"`
+	if len(diffs) != 1 || !strings.HasPrefix(diffs.String(), expected) {
+		t.Fatalf("Bad diffs %s", diffs)
+	}
+	fields := diffs[0].Fields
+	if len(fields) != 4 || fields[2].Field != "parentTemplates[Template C]" || fields[2].Old != nil ||
+		fields[3].Field != "macros[{$PORT}].value" || fields[3].Old != "80" || fields[3].New != "8080" {
+		t.Errorf("Bad fields %#v", fields)
+	}
+}
+
+func TestDiffItemsAndTemplates(t *testing.T) {
+	old := Items{{ItemId: "1", Key: "vfs.fs.size[/, free]", Delay: "60", Error: "x"}}
+	new := Items{{ItemId: "2", Key: "vfs.fs.size[/,free]", Delay: "1m"}}
+	diffs := DiffItems(old, new)
+	if len(diffs) != 1 || len(diffs[0].Fields) != 1 || diffs[0].Fields[0].Field != "delay" {
+		t.Fatalf("Bad diffs %s", diffs)
+	}
+
+	templates := DiffTemplates(
+		Templates{{TemplateId: "1", Host: "T", Items: old}},
+		Templates{{TemplateId: "2", Host: "T", Items: new, Triggers: Triggers{{Description: "down"}}}},
+	)
+	if len(templates) != 2 || templates[0].Key != "T:vfs.fs.size[/,free]" ||
+		templates[1].Kind != "trigger" || templates[1].Action != ChangeCreate {
+		t.Errorf("Bad template diffs %s", templates)
+	}
+}
+
+func TestDiffTriggers(t *testing.T) {
+	old := Triggers{{TriggerId: "1", Description: "load", Expression: "{h:system.cpu.load.last()}>5", Value: "1"}}
+	new := Triggers{{TriggerId: "2", Description: "load", Expression: "{h:system.cpu.load.last()} > 5"}}
+	if diffs := DiffTriggers(old, new); !diffs.Empty() {
+		t.Errorf("Expected no diffs, got %s", diffs)
+	}
+
+	old[0].Recovery_expression = "{h:system.cpu.load.last()}<2"
+	new[0].Recovery_expression = "{h:system.cpu.load.last()} < 2"
+	if diffs := DiffTriggers(old, new); !diffs.Empty() {
+		t.Errorf("Expected no diffs for recovery expression, got %s", diffs)
+	}
+
+	new[0].Expression = "{h:system.cpu.load.last()}>10"
+	diffs := DiffTriggers(old, new)
+	if len(diffs) != 1 || len(diffs[0].Fields) != 1 || diffs[0].Fields[0].Field != "expression" {
+		t.Errorf("Bad diffs %s", diffs)
+	}
+}
@@ -0,0 +1,232 @@
+// low-level discovery rules
+
+package zabbix
+
+import (
+	"github.com/wOvAN/reflector"
+)
+
+type (
+	LLDEvalType          int
+	LLDConditionOperator int
+	LLDOverrideObject    int
+	LLDOverrideOperator  int
+)
+
+const (
+	LLDEvalAndOr  LLDEvalType = 0
+	LLDEvalAnd    LLDEvalType = 1
+	LLDEvalOr     LLDEvalType = 2
+	LLDEvalCustom LLDEvalType = 3 // Formula is used
+
+	LLDConditionMatches    LLDConditionOperator = 8
+	LLDConditionNotMatches LLDConditionOperator = 9
+	LLDConditionExists     LLDConditionOperator = 12
+	LLDConditionNotExists  LLDConditionOperator = 13
+
+	LLDOverrideItemPrototype    LLDOverrideObject = 0
+	LLDOverrideTriggerPrototype LLDOverrideObject = 1
+	LLDOverrideGraphPrototype   LLDOverrideObject = 2
+	LLDOverrideHostPrototype    LLDOverrideObject = 3
+
+	LLDOverrideEquals      LLDOverrideOperator = 0
+	LLDOverrideNotEquals   LLDOverrideOperator = 1
+	LLDOverrideContains    LLDOverrideOperator = 2
+	LLDOverrideNotContains LLDOverrideOperator = 3
+	LLDOverrideMatches     LLDOverrideOperator = 4
+	LLDOverrideNotMatches  LLDOverrideOperator = 5
+)
+
+const (
+	// Discovery rule Selectors
+	SelectFilter        = "selectFilter"
+	SelectLLDMacroPaths = "selectLLDMacroPaths"
+	SelectOverrides     = "selectOverrides"
+)
+
+// https://www.zabbix.com/documentation/5.0/manual/api/reference/discoveryrule/object#lld_rule_filter_condition
+type LLDCondition struct {
+	Macro     string               `json:"macro"`
+	Value     string               `json:"value"`
+	Operator  LLDConditionOperator `json:"operator,omitempty"`
+	FormulaId string               `json:"formulaid,omitempty"`
+}
+
+type LLDConditions []LLDCondition
+
+// https://www.zabbix.com/documentation/5.0/manual/api/reference/discoveryrule/object#lld_rule_filter
+type LLDFilter struct {
+	EvalType   LLDEvalType   `json:"evaltype"`
+	Formula    string        `json:"formula,omitempty"`
+	Conditions LLDConditions `json:"conditions"`
+}
+
+// https://www.zabbix.com/documentation/5.0/manual/api/reference/discoveryrule/object#lld_macro_path
+type LLDMacroPath struct {
+	LLDMacro string `json:"lld_macro"`
+	Path     string `json:"path"`
+}
+
+type LLDMacroPaths []LLDMacroPath
+
+// https://www.zabbix.com/documentation/5.0/manual/api/reference/discoveryrule/object#lld_rule_overrides
+type LLDOverride struct {
+	Name       string                `json:"name"`
+	Step       int                   `json:"step"`
+	Stop       int                   `json:"stop,omitempty"` // 1 - stop processing next overrides if matches
+	Filter     *LLDFilter            `json:"filter,omitempty"`
+	Operations LLDOverrideOperations `json:"operations,omitempty"`
+}
+
+type LLDOverrides []LLDOverride
+
+// https://www.zabbix.com/documentation/5.0/manual/api/reference/discoveryrule/object#lld_override_operation
+// Each Op* field holds object described in documentation, for example Params{"status": 1} for OpStatus.
+type LLDOverrideOperation struct {
+	OperationObject LLDOverrideObject   `json:"operationobject"`
+	Operator        LLDOverrideOperator `json:"operator"`
+	Value           string              `json:"value,omitempty"`
+	OpStatus        Params              `json:"opstatus,omitempty"`
+	OpDiscover      Params              `json:"opdiscover,omitempty"`
+	OpPeriod        Params              `json:"opperiod,omitempty"`
+	OpHistory       Params              `json:"ophistory,omitempty"`
+	OpTrends        Params              `json:"optrends,omitempty"`
+	OpSeverity      Params              `json:"opseverity,omitempty"`
+	OpTag           []Params            `json:"optag,omitempty"`
+	OpTemplate      []Params            `json:"optemplate,omitempty"`
+	OpInventory     Params              `json:"opinventory,omitempty"`
+}
+
+type LLDOverrideOperations []LLDOverrideOperation
+
+// https://www.zabbix.com/documentation/5.0/manual/api/reference/discoveryrule/object
+type DiscoveryRule struct {
+	ItemId       string     `json:"itemid,omitempty"`
+	Delay        string     `json:"delay"`
+	HostId       string     `json:"hostid"`
+	InterfaceId  string     `json:"interfaceid,omitempty"`
+	Key          string     `json:"key_"`
+	Name         string     `json:"name"`
+	Type         ItemType   `json:"type"`
+	Description  string     `json:"description,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	Lifetime     string     `json:"lifetime,omitempty"`
+	Status       ItemStatus `json:"status"`
+	TemplateId   string     `json:"templateid,omitempty"`
+	MasterItemId string     `json:"master_itemid,omitempty"`
+
+	// Returned by discoveryrule.get only with corresponding selectors
+	Filter        *LLDFilter         `json:"filter,omitempty"`
+	LLDMacroPaths LLDMacroPaths      `json:"lld_macro_paths,omitempty"`
+	Preprocessing PreprocessingSteps `json:"preprocessing,omitempty"`
+	Overrides     LLDOverrides       `json:"overrides,omitempty"`
+}
+
+type DiscoveryRules []DiscoveryRule
+
+// Returns ids of all discovery rules.
+func (rules DiscoveryRules) Ids() (res []string) {
+	res = make([]string, len(rules))
+	for i, rule := range rules {
+		res[i] = rule.ItemId
+	}
+	return
+}
+
+// Wrapper for discoveryrule.get: https://www.zabbix.com/documentation/5.0/manual/api/reference/discoveryrule/get
+func (api *API) DiscoveryRulesGet(params Params) (res DiscoveryRules, err error) {
+	if _, present := params["output"]; !present {
+		params["output"] = "extend"
+	}
+	response, err := api.CallWithError("discoveryrule.get", params)
+	if err != nil {
+		return
+	}
+
+	reflector.MapsToStructs2(response.Result.([]interface{}), &res, reflector.Strconv, "json")
+	return
+}
+
+// Gets discovery rules of hosts or templates with filters, LLD macro paths, preprocessing and overrides.
+func (api *API) DiscoveryRulesGetByHostIds(ids []string) (res DiscoveryRules, err error) {
+	return api.DiscoveryRulesGet(Params{
+		"hostids":           ids,
+		SelectFilter:        "extend",
+		SelectLLDMacroPaths: "extend",
+		SelectPreprocessing: "extend",
+		SelectOverrides:     "extend",
+	})
+}
+
+// Gets discovery rule by Id only if there is exactly 1 matching discovery rule.
+func (api *API) DiscoveryRuleGetById(id string) (res *DiscoveryRule, err error) {
+	rules, err := api.DiscoveryRulesGet(Params{"itemids": id})
+	if err != nil {
+		return
+	}
+
+	if len(rules) == 1 {
+		res = &rules[0]
+	} else {
+		e := ExpectedOneResult(len(rules))
+		err = &e
+	}
+	return
+}
+
+// Wrapper for discoveryrule.create: https://www.zabbix.com/documentation/5.0/manual/api/reference/discoveryrule/create
+func (api *API) DiscoveryRulesCreate(rules DiscoveryRules) (err error) {
+	response, err := api.CallWithError("discoveryrule.create", rules)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	itemids := result["itemids"].([]interface{})
+	for i, id := range itemids {
+		rules[i].ItemId = id.(string)
+	}
+	return
+}
+
+// Wrapper for discoveryrule.update: https://www.zabbix.com/documentation/5.0/manual/api/reference/discoveryrule/update
+func (api *API) DiscoveryRulesUpdate(rules DiscoveryRules) (err error) {
+	response, err := api.CallWithError("discoveryrule.update", rules)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	itemids := result["itemids"].([]interface{})
+	if len(rules) != len(itemids) {
+		err = &ExpectedMore{len(rules), len(itemids)}
+	}
+	return
+}
+
+// Wrapper for discoveryrule.delete: https://www.zabbix.com/documentation/5.0/manual/api/reference/discoveryrule/delete
+// Cleans ItemId in all rules elements if call succeed.
+func (api *API) DiscoveryRulesDelete(rules DiscoveryRules) (err error) {
+	err = api.DiscoveryRulesDeleteByIds(rules.Ids())
+	if err == nil {
+		for i := range rules {
+			rules[i].ItemId = ""
+		}
+	}
+	return
+}
+
+// Wrapper for discoveryrule.delete: https://www.zabbix.com/documentation/5.0/manual/api/reference/discoveryrule/delete
+func (api *API) DiscoveryRulesDeleteByIds(ids []string) (err error) {
+	response, err := api.CallWithError("discoveryrule.delete", ids)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	ruleids := result["ruleids"].([]interface{})
+	if len(ids) != len(ruleids) {
+		err = &ExpectedMore{len(ids), len(ruleids)}
+	}
+	return
+}
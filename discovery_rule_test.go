@@ -0,0 +1,101 @@
+package zabbix_test
+
+import (
+	. "."
+	"testing"
+)
+
+func CreateDiscoveryRule(host *Host, t *testing.T) *DiscoveryRule {
+	rules := DiscoveryRules{{
+		HostId:   host.HostId,
+		Key:      "lld.lala",
+		Name:     "LLD for " + host.Host,
+		Type:     ZabbixTrapper,
+		Lifetime: "7d",
+		Filter: &LLDFilter{
+			EvalType:   LLDEvalAnd,
+			Conditions: LLDConditions{{Macro: "{#IFNAME}", Value: "^eth", Operator: LLDConditionMatches}},
+		},
+		LLDMacroPaths: LLDMacroPaths{{LLDMacro: "{#IFNAME}", Path: "$.name"}},
+	}}
+	err := getAPI(t).DiscoveryRulesCreate(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &rules[0]
+}
+
+func DeleteDiscoveryRule(rule *DiscoveryRule, t *testing.T) {
+	err := getAPI(t).DiscoveryRulesDelete(DiscoveryRules{*rule})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoveryRules(t *testing.T) {
+	api := getAPI(t)
+
+	group := CreateHostGroup(t)
+	defer DeleteHostGroup(group, t)
+
+	host := CreateHost(group, t)
+	defer DeleteHost(host, t)
+
+	rule := CreateDiscoveryRule(host, t)
+	defer DeleteDiscoveryRule(rule, t)
+
+	rules, err := api.DiscoveryRulesGetByHostIds([]string{host.HostId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].Filter == nil || len(rules[0].Filter.Conditions) != 1 || len(rules[0].LLDMacroPaths) != 1 {
+		t.Errorf("Bad discovery rules: %#v", rules)
+	}
+
+	itemPrototypes := ItemPrototypes{{
+		HostId:    host.HostId,
+		RuleId:    rule.ItemId,
+		Key:       "net.if.lala[{#IFNAME}]",
+		Name:      "Interface {#IFNAME}",
+		Type:      ZabbixTrapper,
+		ValueType: Unsigned,
+	}}
+	err = api.ItemPrototypesCreate(itemPrototypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	triggerPrototypes := TriggerPrototypes{{
+		Description: "Interface {#IFNAME} is down",
+		Expression:  "{" + host.Host + ":net.if.lala[{#IFNAME}].last()}=0",
+	}}
+	err = api.TriggerPrototypesCreate(triggerPrototypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	itemPrototypes2, err := api.ItemPrototypesGetByRuleId(rule.ItemId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(itemPrototypes2) != 1 {
+		t.Errorf("Bad item prototypes: %#v", itemPrototypes2)
+	}
+
+	triggerPrototypes2, err := api.TriggerPrototypesGetByRuleId(rule.ItemId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(triggerPrototypes2) != 1 {
+		t.Errorf("Bad trigger prototypes: %#v", triggerPrototypes2)
+	}
+
+	err = api.TriggerPrototypesDelete(triggerPrototypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = api.ItemPrototypesDelete(itemPrototypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,721 @@
+// trigger expressions
+
+package zabbix
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Node of parsed trigger expression.
+// String() returns node in Zabbix syntax, so ParseExpression(node.String()) gives equal tree.
+type ExprNode interface {
+	String() string
+}
+
+type (
+	// Binary operation: or, and, =, <>, # (legacy not equal), <, <=, >, >=, +, -, *, /.
+	ExprBinary struct {
+		Op    string
+		Left  ExprNode
+		Right ExprNode
+	}
+
+	// Unary operation: - or not.
+	ExprUnary struct {
+		Op string
+		X  ExprNode
+	}
+
+	// Expression in parentheses, kept to print expression as it was written.
+	ExprParen struct {
+		X ExprNode
+	}
+
+	// Number with optional suffix like 10, 0.5, 5m or 1G, or quoted string like "error" (5.4+ syntax).
+	ExprConstant struct {
+		Value string
+	}
+
+	// User macro like {$MAX_CPU} or {$MAX:"context"}, LLD macro like {#IFNAME} or built-in macro like {TRIGGER.VALUE}.
+	ExprMacro struct {
+		Text string
+	}
+
+	// Function reference like {13055} as returned by trigger.get without expandExpression.
+	ExprFunctionId struct {
+		Id string
+	}
+
+	// Item referenced by function. In 5.4+ syntax Host may be empty (current host) or "*",
+	// and Filter may contain item filter like `tag="cpu"` from /*/key?[tag="cpu"].
+	ExprItemRef struct {
+		Host   string
+		Key    string
+		Filter string
+	}
+
+	// Function call in one of the syntaxes:
+	//   legacy (before 5.4): {host:key.func(params)}
+	//   history function (5.4+): func(/host/key,params)
+	//   other function (5.4+): func(args)
+	// Params hold parameters after item reference as written, for example "#3", "5m" or "\"gt\"".
+	// Args hold arguments of functions without item reference like abs() or avg() of foreach functions.
+	ExprFunction struct {
+		Name   string
+		Item   *ExprItemRef
+		Params []string
+		Args   []ExprNode
+		Legacy bool
+	}
+)
+
+func (n *ExprBinary) String() string {
+	if n.Op == "and" || n.Op == "or" {
+		return n.Left.String() + " " + n.Op + " " + n.Right.String()
+	}
+	return n.Left.String() + n.Op + n.Right.String()
+}
+
+func (n *ExprUnary) String() string {
+	if n.Op == "not" {
+		return "not " + n.X.String()
+	}
+	return n.Op + n.X.String()
+}
+
+func (n *ExprParen) String() string {
+	return "(" + n.X.String() + ")"
+}
+
+func (n *ExprConstant) String() string {
+	return n.Value
+}
+
+func (n *ExprMacro) String() string {
+	return n.Text
+}
+
+func (n *ExprFunctionId) String() string {
+	return "{" + n.Id + "}"
+}
+
+func (n *ExprItemRef) String() string {
+	s := "/" + n.Host + "/" + n.Key
+	if n.Filter != "" {
+		s += "?[" + n.Filter + "]"
+	}
+	return s
+}
+
+func (n *ExprFunction) String() string {
+	if n.Legacy {
+		return "{" + n.Item.Host + ":" + n.Item.Key + "." + n.Name + "(" + strings.Join(n.Params, ",") + ")}"
+	}
+
+	var b bytes.Buffer
+	b.WriteString(n.Name)
+	b.WriteByte('(')
+	if n.Item != nil {
+		b.WriteString(n.Item.String())
+		for _, p := range n.Params {
+			b.WriteByte(',')
+			b.WriteString(p)
+		}
+	} else {
+		for i, a := range n.Args {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(a.String())
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// Returns true if constant is quoted string.
+func (n *ExprConstant) IsString() bool {
+	return strings.HasPrefix(n.Value, `"`)
+}
+
+// Returns function parameter without quotes and escaping. Returns empty string for missing parameter.
+func (n *ExprFunction) Param(i int) string {
+	if i >= len(n.Params) {
+		return ""
+	}
+	return unquoteParam(n.Params[i])
+}
+
+// Calls fn for node and all its descendants in depth-first order, stops descending if fn returns false.
+func WalkExpression(node ExprNode, fn func(ExprNode) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	switch n := node.(type) {
+	case *ExprBinary:
+		WalkExpression(n.Left, fn)
+		WalkExpression(n.Right, fn)
+	case *ExprUnary:
+		WalkExpression(n.X, fn)
+	case *ExprParen:
+		WalkExpression(n.X, fn)
+	case *ExprFunction:
+		for _, a := range n.Args {
+			WalkExpression(a, fn)
+		}
+	}
+}
+
+// Returns all functions used in expression, including nested ones.
+func ExpressionFunctions(node ExprNode) (res []*ExprFunction) {
+	WalkExpression(node, func(n ExprNode) bool {
+		if f, ok := n.(*ExprFunction); ok {
+			res = append(res, f)
+		}
+		return true
+	})
+	return
+}
+
+// Returns all items referenced by expression in order of appearance, without duplicates.
+func ExpressionItemRefs(node ExprNode) (res []ExprItemRef) {
+	seen := make(map[ExprItemRef]bool)
+	for _, f := range ExpressionFunctions(node) {
+		if f.Item != nil && !seen[*f.Item] {
+			seen[*f.Item] = true
+			res = append(res, *f.Item)
+		}
+	}
+	return
+}
+
+// Returns items referenced by expression for which exists returns false.
+// Useful to find triggers which will break because referenced items were removed or renamed.
+func ExpressionMissingItems(node ExprNode, exists func(host, key string) bool) (res []ExprItemRef) {
+	for _, ref := range ExpressionItemRefs(node) {
+		if !exists(ref.Host, ref.Key) {
+			res = append(res, ref)
+		}
+	}
+	return
+}
+
+// Parses trigger expression.
+func (t *Trigger) ParseExpression() (ExprNode, error) {
+	return ParseExpression(t.Expression)
+}
+
+// Parses trigger recovery expression. Returns nil node for empty recovery expression.
+func (t *Trigger) ParseRecoveryExpression() (ExprNode, error) {
+	if strings.TrimSpace(t.Recovery_expression) == "" {
+		return nil, nil
+	}
+	return ParseExpression(t.Recovery_expression)
+}
+
+// Error returned by ParseExpression. Pos is byte offset in Expression.
+type ExpressionError struct {
+	Expression string
+	Pos        int
+	Message    string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("%s at position %d in expression %q", e.Message, e.Pos, e.Expression)
+}
+
+// Parses trigger expression in legacy (before 5.4) or new (5.4+) syntax.
+// Whitespace is not kept: String() of result separates only and, or and not with spaces.
+func ParseExpression(expression string) (node ExprNode, err error) {
+	p := &exprParser{s: expression}
+	node, err = p.parseOr()
+	if err != nil {
+		return
+	}
+	p.skipSpaces()
+	if p.pos < len(p.s) {
+		return nil, p.errorf("unexpected %q", p.s[p.pos:p.pos+1])
+	}
+	return
+}
+
+type exprParser struct {
+	s   string
+	pos int
+}
+
+var (
+	exprNumberRE   = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?[KMGTsmhdw]?`)
+	exprNameRE     = regexp.MustCompile(`^[a-z_][a-z0-9_]*\(`)
+	exprIdRE       = regexp.MustCompile(`^[0-9]+$`)
+	exprBuiltinRE  = regexp.MustCompile(`^[A-Z][A-Z0-9_.]*$`)
+	exprFuncNameRE = regexp.MustCompile(`\.([a-zA-Z_][a-zA-Z0-9_]*)$`)
+)
+
+// Returns true if c may be used in item key name.
+func exprKeyNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.'
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return &ExpressionError{Expression: p.s, Pos: p.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// Consumes keyword if it is next and followed by non-identifier character.
+func (p *exprParser) keyword(word string) bool {
+	p.skipSpaces()
+	if !strings.HasPrefix(p.s[p.pos:], word) {
+		return false
+	}
+	end := p.pos + len(word)
+	if end < len(p.s) {
+		c := p.s[end]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' {
+			return false
+		}
+	}
+	p.pos = end
+	return true
+}
+
+// Consumes one of operators if it is next.
+func (p *exprParser) operator(ops ...string) string {
+	p.skipSpaces()
+	for _, op := range ops {
+		if strings.HasPrefix(p.s[p.pos:], op) {
+			// do not take "<" from "<=" or "<>"
+			if (op == "<" || op == ">") && p.pos+1 < len(p.s) && (p.s[p.pos+1] == '=' || op == "<" && p.s[p.pos+1] == '>') {
+				continue
+			}
+			p.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *exprParser) parseOr() (ExprNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.keyword("or") {
+		var right ExprNode
+		right, err = p.parseAnd()
+		left = &ExprBinary{"or", left, right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAnd() (ExprNode, error) {
+	left, err := p.parseEquality()
+	for err == nil && p.keyword("and") {
+		var right ExprNode
+		right, err = p.parseEquality()
+		left = &ExprBinary{"and", left, right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseEquality() (ExprNode, error) {
+	left, err := p.parseRelational()
+	for err == nil {
+		op := p.operator("=", "<>", "#")
+		if op == "" {
+			break
+		}
+		var right ExprNode
+		right, err = p.parseRelational()
+		left = &ExprBinary{op, left, right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseRelational() (ExprNode, error) {
+	left, err := p.parseAdditive()
+	for err == nil {
+		op := p.operator("<=", ">=", "<", ">")
+		if op == "" {
+			break
+		}
+		var right ExprNode
+		right, err = p.parseAdditive()
+		left = &ExprBinary{op, left, right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAdditive() (ExprNode, error) {
+	left, err := p.parseMultiplicative()
+	for err == nil {
+		op := p.operator("+", "-")
+		if op == "" {
+			break
+		}
+		var right ExprNode
+		right, err = p.parseMultiplicative()
+		left = &ExprBinary{op, left, right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseMultiplicative() (ExprNode, error) {
+	left, err := p.parseUnary()
+	for err == nil {
+		op := p.operator("*", "/")
+		if op == "" {
+			break
+		}
+		var right ExprNode
+		right, err = p.parseUnary()
+		left = &ExprBinary{op, left, right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseUnary() (ExprNode, error) {
+	if p.operator("-") != "" {
+		x, err := p.parseUnary()
+		return &ExprUnary{"-", x}, err
+	}
+	if p.keyword("not") {
+		x, err := p.parseUnary()
+		return &ExprUnary{"not", x}, err
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (ExprNode, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.s) {
+		return nil, p.errorf("unexpected end of expression")
+	}
+
+	rest := p.s[p.pos:]
+	switch {
+	case rest[0] == '(':
+		p.pos++
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.operator(")") == "" {
+			return nil, p.errorf("expected )")
+		}
+		return &ExprParen{x}, nil
+
+	case rest[0] == '{':
+		return p.parseBraces()
+
+	case rest[0] == '"':
+		end, err := p.scanQuoted(p.pos)
+		if err != nil {
+			return nil, err
+		}
+		n := &ExprConstant{p.s[p.pos:end]}
+		p.pos = end
+		return n, nil
+
+	case exprNumberRE.MatchString(rest):
+		m := exprNumberRE.FindString(rest)
+		p.pos += len(m)
+		return &ExprConstant{m}, nil
+
+	case exprNameRE.MatchString(rest):
+		return p.parseFunction()
+	}
+	return nil, p.errorf("unexpected %q", rest[:1])
+}
+
+// Parses macro, function id or legacy function starting with "{".
+func (p *exprParser) parseBraces() (ExprNode, error) {
+	start := p.pos
+	if strings.HasPrefix(p.s[start:], "{$") || strings.HasPrefix(p.s[start:], "{#") {
+		end, err := p.scanMacro(start)
+		if err != nil {
+			return nil, err
+		}
+		p.pos = end
+		return &ExprMacro{p.s[start:end]}, nil
+	}
+
+	if end := strings.IndexByte(p.s[start:], '}'); end > 0 {
+		inner := p.s[start+1 : start+end]
+		if exprIdRE.MatchString(inner) {
+			p.pos = start + end + 1
+			return &ExprFunctionId{inner}, nil
+		}
+		if exprBuiltinRE.MatchString(inner) {
+			p.pos = start + end + 1
+			return &ExprMacro{p.s[start : start+end+1]}, nil
+		}
+	}
+
+	// legacy function {host:key.func(params)}
+	colon := strings.IndexByte(p.s[start:], ':')
+	if colon < 0 {
+		return nil, p.errorf("expected host:key.function(params)")
+	}
+	host := p.s[start+1 : start+colon]
+	p.pos = start + colon + 1
+
+	keyStart := p.pos
+	keyEnd, err := scanItemKey(p.s, keyStart)
+	if err != nil {
+		p.pos = keyEnd
+		return nil, p.errorf("%s", err)
+	}
+	key := p.s[keyStart:keyEnd]
+	var name string
+	if strings.HasSuffix(key, "]") {
+		// key with parameters: function name follows "]."
+		p.pos = keyEnd
+		if p.pos >= len(p.s) || p.s[p.pos] != '.' {
+			return nil, p.errorf("expected .function after item key")
+		}
+		nameEnd := p.pos + 1
+		for nameEnd < len(p.s) && exprKeyNameChar(p.s[nameEnd]) && p.s[nameEnd] != '.' {
+			nameEnd++
+		}
+		name = p.s[p.pos+1 : nameEnd]
+		p.pos = nameEnd
+	} else {
+		// key without parameters: function name is after last dot
+		m := exprFuncNameRE.FindStringSubmatch(key)
+		if m == nil {
+			return nil, p.errorf("expected .function after item key")
+		}
+		name = m[1]
+		key = key[:len(key)-len(m[0])]
+		p.pos = keyEnd
+	}
+	if name == "" || key == "" {
+		return nil, p.errorf("expected host:key.function(params)")
+	}
+
+	if p.pos >= len(p.s) || p.s[p.pos] != '(' {
+		return nil, p.errorf("expected ( after function name")
+	}
+	params, err := p.parseRawParams(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.s) || p.s[p.pos] != '}' {
+		return nil, p.errorf("expected }")
+	}
+	p.pos++
+	return &ExprFunction{Name: name, Item: &ExprItemRef{Host: host, Key: key}, Params: params, Legacy: true}, nil
+}
+
+// Parses 5.4+ function call.
+func (p *exprParser) parseFunction() (ExprNode, error) {
+	open := strings.IndexByte(p.s[p.pos:], '(')
+	f := &ExprFunction{Name: p.s[p.pos : p.pos+open]}
+	p.pos += open
+
+	// history function: first argument is item reference
+	rest := strings.TrimLeft(p.s[p.pos+1:], " ")
+	if strings.HasPrefix(rest, "/") {
+		p.pos = len(p.s) - len(rest)
+		item, err := p.parseItemRef()
+		if err != nil {
+			return nil, err
+		}
+		f.Item = item
+		p.skipSpaces()
+		if p.pos < len(p.s) && p.s[p.pos] == ',' {
+			f.Params, err = p.parseRawParams(true)
+			if err != nil {
+				return nil, err
+			}
+		} else if p.pos < len(p.s) && p.s[p.pos] == ')' {
+			p.pos++
+		} else {
+			return nil, p.errorf("expected , or )")
+		}
+		return f, nil
+	}
+
+	p.pos++
+	if p.operator(")") != "" {
+		return f, nil
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		f.Args = append(f.Args, arg)
+		if p.operator(",") != "" {
+			continue
+		}
+		if p.operator(")") != "" {
+			return f, nil
+		}
+		return nil, p.errorf("expected , or )")
+	}
+}
+
+// Parses /host/key or /host/key?[filter].
+func (p *exprParser) parseItemRef() (*ExprItemRef, error) {
+	p.pos++ // skip "/"
+	slash := strings.IndexByte(p.s[p.pos:], '/')
+	if slash < 0 {
+		return nil, p.errorf("expected /host/key")
+	}
+	ref := &ExprItemRef{Host: p.s[p.pos : p.pos+slash]}
+	p.pos += slash + 1
+
+	end, err := scanItemKey(p.s, p.pos)
+	if err != nil {
+		p.pos = end
+		return nil, p.errorf("%s", err)
+	}
+	if end == p.pos {
+		return nil, p.errorf("expected item key")
+	}
+	ref.Key = p.s[p.pos:end]
+	p.pos = end
+
+	if strings.HasPrefix(p.s[p.pos:], "?[") {
+		start := p.pos + 2
+		depth := 1
+		i := start
+		for ; i < len(p.s) && depth > 0; i++ {
+			switch p.s[i] {
+			case '"':
+				end, err := p.scanQuoted(i)
+				if err != nil {
+					return nil, err
+				}
+				i = end - 1
+			case '[':
+				depth++
+			case ']':
+				depth--
+			}
+		}
+		if depth > 0 {
+			return nil, p.errorf("unterminated item filter")
+		}
+		ref.Filter = p.s[start : i-1]
+		p.pos = i
+	}
+	return ref, nil
+}
+
+// Parses parameters as written from "(" or "," to closing ")", respecting quotes and nested parentheses.
+// If afterComma is false, empty parameter list "()" gives no parameters.
+func (p *exprParser) parseRawParams(afterComma bool) (params []string, err error) {
+	p.pos++ // skip "(" or ","
+	start := p.pos
+	depth := 0
+	for p.pos < len(p.s) {
+		switch c := p.s[p.pos]; c {
+		case '"':
+			end, err := p.scanQuoted(p.pos)
+			if err != nil {
+				return nil, err
+			}
+			p.pos = end
+			continue
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				last := strings.TrimSpace(p.s[start:p.pos])
+				if last != "" || len(params) > 0 || afterComma {
+					params = append(params, last)
+				}
+				p.pos++
+				return
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				params = append(params, strings.TrimSpace(p.s[start:p.pos]))
+				start = p.pos + 1
+			}
+		}
+		p.pos++
+	}
+	return nil, p.errorf("expected )")
+}
+
+// Returns position after closing quote of string starting at pos.
+func (p *exprParser) scanQuoted(pos int) (int, error) {
+	for i := pos + 1; i < len(p.s); i++ {
+		switch p.s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	p.pos = pos
+	return 0, p.errorf("unterminated string")
+}
+
+// Returns position after closing brace of user or LLD macro starting at pos.
+func (p *exprParser) scanMacro(pos int) (int, error) {
+	for i := pos + 1; i < len(p.s); i++ {
+		switch p.s[i] {
+		case '"':
+			end, err := p.scanQuoted(i)
+			if err != nil {
+				return 0, err
+			}
+			i = end - 1
+		case '}':
+			return i + 1, nil
+		}
+	}
+	p.pos = pos
+	return 0, p.errorf("unterminated macro")
+}
+
+// Returns position after item key starting at pos: key name and optional parameters in brackets.
+func scanItemKey(s string, pos int) (int, error) {
+	i := pos
+	for i < len(s) && exprKeyNameChar(s[i]) {
+		i++
+	}
+	if i >= len(s) || s[i] != '[' {
+		return i, nil
+	}
+
+	depth := 0
+	quoted := false
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(s) && s[i+1] == '"':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return i, fmt.Errorf("unterminated item key parameters")
+}
+
+// Removes quotes and escaping from function parameter.
+func unquoteParam(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	return strings.Replace(s[1:len(s)-1], `\"`, `"`, -1)
+}
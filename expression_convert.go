@@ -0,0 +1,374 @@
+// trigger expression conversion between syntaxes
+
+package zabbix
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type ExpressionSyntax int
+
+const (
+	LegacySyntax ExpressionSyntax = 0 // {host:key.func(params)}, before Zabbix 5.4
+	NewSyntax    ExpressionSyntax = 1 // func(/host/key,params), Zabbix 5.4 and later
+)
+
+// Error returned when parts of expression can't be converted. Such parts are left as is.
+type ConversionError struct {
+	Expression string
+	Problems   []string
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("Can't convert expression %q: %s", e.Expression, strings.Join(e.Problems, "; "))
+}
+
+// Converts trigger expression to given syntax. Parts already in that syntax are kept.
+// If some functions can't be converted, result contains them unchanged and err is *ConversionError.
+func ConvertExpression(expression string, to ExpressionSyntax) (res string, err error) {
+	node, err := ParseExpression(expression)
+	if err != nil {
+		return
+	}
+	node, problems := ConvertExpressionNode(node, to)
+	res = node.String()
+	if len(problems) > 0 {
+		err = &ConversionError{Expression: expression, Problems: problems}
+	}
+	return
+}
+
+// Converts expression and recovery expression of trigger to given syntax in place.
+// Expressions are changed even if error is returned, see ConvertExpression.
+func (t *Trigger) ConvertExpressions(to ExpressionSyntax) (err error) {
+	var problems []string
+	t.Expression, err = ConvertExpression(t.Expression, to)
+	if e, ok := err.(*ConversionError); ok {
+		problems = append(problems, e.Problems...)
+	} else if err != nil {
+		return
+	}
+
+	if strings.TrimSpace(t.Recovery_expression) != "" {
+		t.Recovery_expression, err = ConvertExpression(t.Recovery_expression, to)
+		if e, ok := err.(*ConversionError); ok {
+			problems = append(problems, e.Problems...)
+		} else if err != nil {
+			return
+		}
+	}
+
+	err = nil
+	if len(problems) > 0 {
+		err = &ConversionError{Expression: t.Expression, Problems: problems}
+	}
+	return
+}
+
+// Converts parsed expression to given syntax. Returns new tree and descriptions of parts which can't be converted.
+func ConvertExpressionNode(node ExprNode, to ExpressionSyntax) (res ExprNode, problems []string) {
+	c := &exprConverter{}
+	if to == LegacySyntax {
+		// functions without item like now() need some item in legacy syntax
+		for _, ref := range ExpressionItemRefs(node) {
+			if ref.Host != "" && ref.Filter == "" {
+				r := ref
+				c.anyItem = &r
+				break
+			}
+		}
+	}
+	res = c.convert(node, to)
+	return res, c.problems
+}
+
+type exprConverter struct {
+	problems []string
+	anyItem  *ExprItemRef
+}
+
+func (c *exprConverter) problemf(format string, args ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+func (c *exprConverter) convert(node ExprNode, to ExpressionSyntax) ExprNode {
+	switch n := node.(type) {
+	case *ExprBinary:
+		op := n.Op
+		if op == "#" {
+			op = "<>"
+		}
+		return &ExprBinary{op, c.convert(n.Left, to), c.convert(n.Right, to)}
+	case *ExprUnary:
+		return &ExprUnary{n.Op, c.convert(n.X, to)}
+	case *ExprParen:
+		return &ExprParen{c.convert(n.X, to)}
+	case *ExprFunctionId:
+		c.problemf("function id %s can't be converted, get trigger with expanded expression", n)
+		return n
+	case *ExprFunction:
+		if n.Legacy && to == NewSyntax {
+			return c.toNew(n)
+		}
+		if !n.Legacy && to == LegacySyntax {
+			return c.toLegacy(n)
+		}
+		if !n.Legacy {
+			args := make([]ExprNode, len(n.Args))
+			for i, a := range n.Args {
+				args[i] = c.convert(a, to)
+			}
+			f := *n
+			f.Args = args
+			return &f
+		}
+	}
+	return node
+}
+
+var exprPlainNumberRE = regexp.MustCompile(`^[0-9]+$`)
+
+// Converts legacy period (seconds or #num) and time shift to 5.4+ period like "5m", "#3" or "1h:now-1d".
+func newPeriod(period, shift string) string {
+	if exprPlainNumberRE.MatchString(period) {
+		period += "s"
+	}
+	if shift != "" {
+		if exprPlainNumberRE.MatchString(shift) {
+			shift += "s"
+		}
+		if period == "" {
+			period = "#1"
+		}
+		period += ":now-" + shift
+	}
+	return period
+}
+
+// Quotes legacy function parameter if it is not quoted yet, 5.4+ syntax requires quoted strings.
+func quoteParam(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		return s
+	}
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
+// Creates 5.4+ history function, trailing empty parameters are removed.
+func newHistoryFunction(name string, item *ExprItemRef, params ...string) *ExprFunction {
+	for len(params) > 0 && params[len(params)-1] == "" {
+		params = params[:len(params)-1]
+	}
+	return &ExprFunction{Name: name, Item: &ExprItemRef{Host: item.Host, Key: item.Key}, Params: params}
+}
+
+func (c *exprConverter) toNew(f *ExprFunction) ExprNode {
+	p := func(i int) string {
+		if i < len(f.Params) {
+			return f.Params[i]
+		}
+		return ""
+	}
+	item := f.Item
+
+	switch f.Name {
+	case "last":
+		period := p(0)
+		if !strings.HasPrefix(period, "#") {
+			period = "" // seconds are ignored by legacy last()
+		}
+		return newHistoryFunction("last", item, newPeriod(period, p(1)))
+	case "prev":
+		return newHistoryFunction("last", item, "#2")
+	case "avg", "min", "max", "sum":
+		return newHistoryFunction(f.Name, item, newPeriod(p(0), p(1)))
+	case "percentile":
+		return newHistoryFunction("percentile", item, newPeriod(p(0), p(1)), p(2))
+	case "count":
+		operator, pattern := p(2), p(1)
+		if pattern != "" {
+			pattern = quoteParam(pattern)
+			if operator == "" {
+				operator = `"eq"`
+			}
+		}
+		if operator != "" {
+			operator = quoteParam(operator)
+		}
+		return newHistoryFunction("count", item, newPeriod(p(0), p(3)), operator, pattern)
+	case "change":
+		return newHistoryFunction("change", item)
+	case "abschange":
+		return &ExprFunction{Name: "abs", Args: []ExprNode{newHistoryFunction("change", item)}}
+	case "diff":
+		return &ExprParen{&ExprBinary{"<>", newHistoryFunction("last", item, "#1"), newHistoryFunction("last", item, "#2")}}
+	case "delta":
+		period := newPeriod(p(0), p(1))
+		return &ExprParen{&ExprBinary{"-", newHistoryFunction("max", item, period), newHistoryFunction("min", item, period)}}
+	case "nodata":
+		// mode like strict is quoted string in 5.4+ syntax
+		return newHistoryFunction("nodata", item, newPeriod(p(0), ""), quoteOptionalParam(p(1)))
+	case "fuzzytime":
+		if p(1) != "" {
+			c.problemf("parameter %s of fuzzytime() can't be converted", p(1))
+			return f
+		}
+		return newHistoryFunction("fuzzytime", item, newPeriod(p(0), ""))
+	case "str", "regexp", "iregexp":
+		operator := map[string]string{"str": `"like"`, "regexp": `"regexp"`, "iregexp": `"iregexp"`}[f.Name]
+		return newHistoryFunction("find", item, newPeriod(p(1), ""), operator, quoteParam(p(0)))
+	case "strlen":
+		return &ExprFunction{Name: "length", Args: []ExprNode{newHistoryFunction("last", item, newPeriod(p(0), p(1)))}}
+	case "band":
+		return &ExprFunction{Name: "bitand", Args: []ExprNode{
+			newHistoryFunction("last", item, newPeriod(p(0), p(2))),
+			&ExprConstant{p(1)},
+		}}
+	case "logeventid", "logsource":
+		if p(0) == "" {
+			return newHistoryFunction(f.Name, item)
+		}
+		return newHistoryFunction(f.Name, item, "", quoteParam(p(0)))
+	case "logseverity":
+		return newHistoryFunction("logseverity", item)
+	case "forecast":
+		return newHistoryFunction("forecast", item, newPeriod(p(0), p(1)), p(2), quoteOptionalParam(p(3)), quoteOptionalParam(p(4)))
+	case "timeleft":
+		return newHistoryFunction("timeleft", item, newPeriod(p(0), p(1)), p(2), quoteOptionalParam(p(3)))
+	case "date", "time", "now", "dayofweek", "dayofmonth":
+		return &ExprFunction{Name: f.Name}
+	}
+
+	c.problemf("function %s() can't be converted", f.Name)
+	return f
+}
+
+// Quotes parameter unless it is empty.
+func quoteOptionalParam(s string) string {
+	if s == "" {
+		return ""
+	}
+	return quoteParam(s)
+}
+
+var exprShiftRE = regexp.MustCompile(`^(#?[0-9]+[smhdw]?)?:now-([0-9]+[smhdw]?)$`)
+
+// Splits 5.4+ period to legacy period and time shift.
+func legacyPeriod(period string) (p, shift string, ok bool) {
+	if !strings.Contains(period, ":") {
+		return period, "", !strings.Contains(period, "/")
+	}
+	m := exprShiftRE.FindStringSubmatch(period)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// Creates legacy function, trailing empty parameters are removed.
+func newLegacyFunction(name string, item *ExprItemRef, params ...string) *ExprFunction {
+	for len(params) > 0 && params[len(params)-1] == "" {
+		params = params[:len(params)-1]
+	}
+	return &ExprFunction{Name: name, Item: &ExprItemRef{Host: item.Host, Key: item.Key}, Params: params, Legacy: true}
+}
+
+func (c *exprConverter) toLegacy(f *ExprFunction) ExprNode {
+	if f.Item == nil {
+		return c.toLegacyMath(f)
+	}
+	if f.Item.Host == "" || f.Item.Host == "*" || f.Item.Filter != "" {
+		c.problemf("item reference %s can't be converted", f.Item)
+		return f
+	}
+
+	p := func(i int) string {
+		if i < len(f.Params) {
+			return f.Params[i]
+		}
+		return ""
+	}
+	period, shift, ok := legacyPeriod(p(0))
+	if !ok {
+		c.problemf("period %s of %s() can't be converted", p(0), f.Name)
+		return f
+	}
+	item := f.Item
+
+	switch f.Name {
+	case "last":
+		if period == "" && shift != "" {
+			period = "#1"
+		}
+		return newLegacyFunction("last", item, period, shift)
+	case "avg", "min", "max", "sum", "change", "fuzzytime", "logseverity":
+		return newLegacyFunction(f.Name, item, period, shift)
+	case "nodata":
+		if shift != "" {
+			break
+		}
+		return newLegacyFunction("nodata", item, period, unquoteParam(p(1)))
+	case "percentile":
+		return newLegacyFunction("percentile", item, period, shift, p(1))
+	case "count":
+		return newLegacyFunction("count", item, period, p(2), p(1), shift)
+	case "find":
+		name := map[string]string{"": "str", `"like"`: "str", `"regexp"`: "regexp", `"iregexp"`: "iregexp"}[p(1)]
+		if name == "" || shift != "" {
+			break
+		}
+		return newLegacyFunction(name, item, p(2), period)
+	case "logeventid", "logsource":
+		return newLegacyFunction(f.Name, item, p(1))
+	case "forecast":
+		return newLegacyFunction("forecast", item, period, shift, p(1), p(2), p(3))
+	case "timeleft":
+		return newLegacyFunction("timeleft", item, period, shift, p(1), p(2))
+	}
+
+	c.problemf("function %s() can't be converted", f.Name)
+	return f
+}
+
+// Converts 5.4+ functions without item reference.
+func (c *exprConverter) toLegacyMath(f *ExprFunction) ExprNode {
+	switch f.Name {
+	case "date", "time", "now", "dayofweek", "dayofmonth":
+		if c.anyItem == nil {
+			c.problemf("function %s() requires item in legacy syntax", f.Name)
+			return f
+		}
+		return newLegacyFunction(f.Name, c.anyItem)
+	}
+
+	// functions over single history function
+	if len(f.Args) >= 1 {
+		if inner, ok := f.Args[0].(*ExprFunction); ok && inner.Item != nil && !inner.Legacy {
+			switch {
+			case f.Name == "abs" && len(f.Args) == 1 && inner.Name == "change" && len(inner.Params) == 0:
+				return newLegacyFunction("abschange", inner.Item)
+			case f.Name == "length" && len(f.Args) == 1 && inner.Name == "last":
+				if l, ok := c.toLegacy(inner).(*ExprFunction); ok && l.Legacy {
+					return newLegacyFunction("strlen", l.Item, l.Params...)
+				}
+			case f.Name == "bitand" && len(f.Args) == 2 && inner.Name == "last":
+				mask, ok := f.Args[1].(*ExprConstant)
+				if l, ok2 := c.toLegacy(inner).(*ExprFunction); ok && ok2 && l.Legacy {
+					// band(#num|sec,mask,time_shift)
+					period, shift := "", ""
+					if len(l.Params) > 0 {
+						period = l.Params[0]
+					}
+					if len(l.Params) > 1 {
+						shift = l.Params[1]
+					}
+					return newLegacyFunction("band", l.Item, period, mask.Value, shift)
+				}
+			}
+		}
+	}
+
+	c.problemf("function %s() can't be converted", f.Name)
+	return f
+}
@@ -0,0 +1,492 @@
+// local trigger expression evaluation
+
+package zabbix
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Evaluates trigger expressions locally against sample history, without Zabbix server.
+// Supported functions are last, prev, avg, min, max, sum, count, change, abschange, diff, delta, nodata,
+// abs, date, time, now, dayofweek and dayofmonth in both legacy and 5.4+ syntax.
+type Evaluator struct {
+	History map[ExprItemRef]HistoryItems // values of items, Filter of keys is not used
+	Macros  map[string]string            // user macros like "{$MAX_CPU}": "90"
+
+	triggerValue int // value of {TRIGGER.VALUE} macro
+}
+
+// Creates evaluator without history and macros.
+func NewEvaluator() *Evaluator {
+	return &Evaluator{History: make(map[ExprItemRef]HistoryItems), Macros: make(map[string]string)}
+}
+
+// Sets history of item on host. Values may be in any order.
+func (e *Evaluator) SetHistory(host, key string, values HistoryItems) {
+	sorted := make(HistoryItems, len(values))
+	copy(sorted, values)
+	sort.Sort(historyByTime(sorted))
+	e.History[ExprItemRef{Host: host, Key: key}] = sorted
+}
+
+type historyByTime HistoryItems
+
+func (h historyByTime) Len() int           { return len(h) }
+func (h historyByTime) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h historyByTime) Less(i, j int) bool { return h[i].Time().Before(h[j].Time()) }
+
+// Error returned when expression value can't be calculated, for example because there is not enough data.
+// Zabbix server would set trigger state to unknown in this case.
+type EvalError struct {
+	Node    string
+	Message string
+}
+
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Node, e.Message)
+}
+
+func evalErrorf(node ExprNode, format string, args ...interface{}) error {
+	return &EvalError{Node: node.String(), Message: fmt.Sprintf(format, args...)}
+}
+
+// Evaluates parsed expression at time now. Legacy functions are converted to 5.4+ syntax first.
+func (e *Evaluator) Evaluate(node ExprNode, now time.Time) (float64, error) {
+	converted, problems := ConvertExpressionNode(node, NewSyntax)
+	if len(problems) > 0 {
+		return 0, &EvalError{Node: node.String(), Message: strings.Join(problems, "; ")}
+	}
+	return e.eval(converted, now)
+}
+
+// Parses and evaluates expression at time now.
+func (e *Evaluator) EvaluateString(expression string, now time.Time) (float64, error) {
+	node, err := ParseExpression(expression)
+	if err != nil {
+		return 0, err
+	}
+	return e.Evaluate(node, now)
+}
+
+// Trigger state change found by Simulate.
+type TriggerEvent struct {
+	Time  time.Time
+	Value int // 1 - problem, 0 - OK
+}
+
+// Evaluates trigger at every history value time in [from, to] (or every step if step is not zero)
+// and returns moments when trigger would fire and recover.
+// Trigger recovery mode and recovery expression are honoured; {TRIGGER.VALUE} macro holds current state.
+// Moments when expression can't be evaluated (unknown state) do not change trigger state.
+func (e *Evaluator) Simulate(trigger *Trigger, from, to time.Time, step time.Duration) (events []TriggerEvent, err error) {
+	problem, err := trigger.ParseExpression()
+	if err != nil {
+		return
+	}
+	recovery, err := trigger.ParseRecoveryExpression()
+	if err != nil {
+		return
+	}
+	mode := fmt.Sprint(trigger.RecoveryMode)
+
+	var moments []time.Time
+	if step > 0 {
+		for t := from; !t.After(to); t = t.Add(step) {
+			moments = append(moments, t)
+		}
+	} else {
+		seen := make(map[int64]bool)
+		for _, values := range e.History {
+			for _, v := range values {
+				t := v.Time()
+				if !t.Before(from) && !t.After(to) && !seen[t.UnixNano()] {
+					seen[t.UnixNano()] = true
+					moments = append(moments, t)
+				}
+			}
+		}
+		sort.Sort(timesAscending(moments))
+	}
+
+	e.triggerValue = 0
+	defer func() { e.triggerValue = 0 }()
+	for _, t := range moments {
+		v, err := e.Evaluate(problem, t)
+		if err != nil {
+			continue
+		}
+		if e.triggerValue == 0 {
+			if v != 0 {
+				e.triggerValue = 1
+				events = append(events, TriggerEvent{t, 1})
+			}
+			continue
+		}
+
+		recovered := v == 0
+		switch mode {
+		case "1": // recovery expression
+			if recovery != nil {
+				r, err := e.Evaluate(recovery, t)
+				recovered = err == nil && v == 0 && r != 0
+			}
+		case "2": // none
+			recovered = false
+		}
+		if recovered {
+			e.triggerValue = 0
+			events = append(events, TriggerEvent{t, 0})
+		}
+	}
+	return
+}
+
+type timesAscending []time.Time
+
+func (t timesAscending) Len() int           { return len(t) }
+func (t timesAscending) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t timesAscending) Less(i, j int) bool { return t[i].Before(t[j]) }
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Zabbix compares floating point values with this precision.
+const evalEpsilon = 0.000001
+
+func (e *Evaluator) eval(node ExprNode, now time.Time) (float64, error) {
+	switch n := node.(type) {
+	case *ExprParen:
+		return e.eval(n.X, now)
+
+	case *ExprUnary:
+		x, err := e.eval(n.X, now)
+		if err != nil {
+			return 0, err
+		}
+		if n.Op == "not" {
+			return boolToFloat(x == 0), nil
+		}
+		return -x, nil
+
+	case *ExprBinary:
+		l, err := e.eval(n.Left, now)
+		if err != nil {
+			// Zabbix evaluates "or" and "and" with unknown operand if result is known anyway
+			if n.Op == "or" || n.Op == "and" {
+				r, err2 := e.eval(n.Right, now)
+				if err2 == nil && (n.Op == "or" && r != 0 || n.Op == "and" && r == 0) {
+					return boolToFloat(r != 0), nil
+				}
+			}
+			return 0, err
+		}
+		if n.Op == "or" && l != 0 || n.Op == "and" && l == 0 {
+			return boolToFloat(l != 0), nil
+		}
+		r, err := e.eval(n.Right, now)
+		if err != nil {
+			return 0, err
+		}
+		switch n.Op {
+		case "or", "and":
+			return boolToFloat(r != 0), nil
+		case "=":
+			return boolToFloat(math.Abs(l-r) <= evalEpsilon), nil
+		case "<>", "#":
+			return boolToFloat(math.Abs(l-r) > evalEpsilon), nil
+		case "<":
+			return boolToFloat(l < r-evalEpsilon), nil
+		case "<=":
+			return boolToFloat(l <= r+evalEpsilon), nil
+		case ">":
+			return boolToFloat(l > r+evalEpsilon), nil
+		case ">=":
+			return boolToFloat(l >= r-evalEpsilon), nil
+		case "+":
+			return l + r, nil
+		case "-":
+			return l - r, nil
+		case "*":
+			return l * r, nil
+		case "/":
+			if r == 0 {
+				return 0, evalErrorf(n, "division by zero")
+			}
+			return l / r, nil
+		}
+		return 0, evalErrorf(n, "unsupported operator %s", n.Op)
+
+	case *ExprConstant:
+		if n.IsString() {
+			return 0, evalErrorf(n, "string is not a number")
+		}
+		return parseEvalNumber(n, n.Value)
+
+	case *ExprMacro:
+		if n.Text == "{TRIGGER.VALUE}" {
+			return float64(e.triggerValue), nil
+		}
+		value, ok := e.Macros[n.Text]
+		if !ok {
+			// fall back to macro without context
+			if i := strings.IndexByte(n.Text, ':'); i > 0 {
+				value, ok = e.Macros[n.Text[:i]+"}"]
+			}
+		}
+		if !ok {
+			return 0, evalErrorf(n, "macro is not defined")
+		}
+		return parseEvalNumber(n, value)
+
+	case *ExprFunction:
+		if n.Item != nil {
+			return e.evalHistoryFunction(n, now)
+		}
+		return e.evalFunction(n, now)
+	}
+	return 0, evalErrorf(node, "can't evaluate")
+}
+
+var exprSuffixes = map[byte]float64{
+	's': 1, 'm': 60, 'h': 3600, 'd': 86400, 'w': 7 * 86400,
+	'K': 1024, 'M': 1024 * 1024, 'G': 1024 * 1024 * 1024, 'T': 1024 * 1024 * 1024 * 1024,
+}
+
+// Parses number with optional suffix like 5m or 1G.
+func parseEvalNumber(node ExprNode, s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	multiplier := 1.0
+	if s != "" {
+		if m, ok := exprSuffixes[s[len(s)-1]]; ok {
+			multiplier = m
+			s = s[:len(s)-1]
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, evalErrorf(node, "%q is not a number", s)
+	}
+	return v * multiplier, nil
+}
+
+func (e *Evaluator) evalFunction(f *ExprFunction, now time.Time) (float64, error) {
+	switch f.Name {
+	case "now":
+		return float64(now.Unix()), nil
+	case "time":
+		h, m, s := now.Clock()
+		return float64(h*10000 + m*100 + s), nil
+	case "date":
+		y, mon, d := now.Date()
+		return float64(y*10000 + int(mon)*100 + d), nil
+	case "dayofweek":
+		wd := int(now.Weekday())
+		if wd == 0 {
+			wd = 7
+		}
+		return float64(wd), nil
+	case "dayofmonth":
+		return float64(now.Day()), nil
+	case "abs":
+		if len(f.Args) != 1 {
+			return 0, evalErrorf(f, "expected 1 argument")
+		}
+		x, err := e.eval(f.Args[0], now)
+		return math.Abs(x), err
+	}
+	return 0, evalErrorf(f, "unsupported function")
+}
+
+// Selects values for period parameter like "5m", "#3" or "1h:now-1d" relative to now, oldest first.
+func (e *Evaluator) selectValues(f *ExprFunction, period string, now time.Time) (res []float64, err error) {
+	values := e.History[ExprItemRef{Host: f.Item.Host, Key: f.Item.Key}]
+
+	end := now
+	if i := strings.Index(period, ":now-"); i >= 0 {
+		shift, err := parseEvalNumber(f, period[i+5:])
+		if err != nil {
+			return nil, err
+		}
+		end = now.Add(-time.Duration(shift * float64(time.Second)))
+		period = period[:i]
+	} else if strings.Contains(period, ":") {
+		return nil, evalErrorf(f, "unsupported time shift %s", period)
+	}
+
+	// values up to end
+	var upToEnd HistoryItems
+	for _, v := range values {
+		if t := v.Time(); !t.After(end) {
+			upToEnd = append(upToEnd, v)
+		}
+	}
+
+	if period == "" || strings.HasPrefix(period, "#") {
+		count := 1
+		if period != "" {
+			count, err = strconv.Atoi(period[1:])
+			if err != nil || count < 1 {
+				return nil, evalErrorf(f, "invalid period %s", period)
+			}
+		}
+		if len(upToEnd) < count {
+			return nil, evalErrorf(f, "not enough data")
+		}
+		for i := len(upToEnd) - count; i < len(upToEnd); i++ {
+			res = append(res, upToEnd[i].Value)
+		}
+		return
+	}
+
+	seconds, err := parseEvalNumber(f, period)
+	if err != nil {
+		return nil, err
+	}
+	start := end.Add(-time.Duration(seconds * float64(time.Second)))
+	for _, v := range upToEnd {
+		if v.Time().After(start) {
+			res = append(res, v.Value)
+		}
+	}
+	return
+}
+
+func (e *Evaluator) evalHistoryFunction(f *ExprFunction, now time.Time) (float64, error) {
+	switch f.Name {
+	case "last":
+		period := f.Param(0)
+		values, err := e.selectValues(f, period, now)
+		if err != nil {
+			return 0, err
+		}
+		// last(/h/k,#3) is third value from the end, which is first of three selected
+		return values[0], nil
+
+	case "change":
+		values, err := e.selectValues(f, "#2", now)
+		if err != nil {
+			return 0, err
+		}
+		return values[1] - values[0], nil
+
+	case "nodata":
+		seconds, err := parseEvalNumber(f, f.Param(0))
+		if err != nil {
+			return 0, err
+		}
+		start := now.Add(-time.Duration(seconds * float64(time.Second)))
+		for _, v := range e.History[ExprItemRef{Host: f.Item.Host, Key: f.Item.Key}] {
+			if t := v.Time(); t.After(start) && !t.After(now) {
+				return 0, nil
+			}
+		}
+		return 1, nil
+
+	case "avg", "min", "max", "sum", "count":
+		period := f.Param(0)
+		if period == "" {
+			return 0, evalErrorf(f, "period is required")
+		}
+		values, err := e.selectValues(f, period, now)
+		if err != nil {
+			return 0, err
+		}
+		if f.Name == "count" {
+			return e.count(f, values)
+		}
+		if len(values) == 0 {
+			if f.Name == "sum" {
+				return 0, nil
+			}
+			return 0, evalErrorf(f, "no data in period")
+		}
+		res := values[0]
+		for _, v := range values[1:] {
+			switch f.Name {
+			case "min":
+				res = math.Min(res, v)
+			case "max":
+				res = math.Max(res, v)
+			default:
+				res += v
+			}
+		}
+		if f.Name == "avg" {
+			res /= float64(len(values))
+		}
+		return res, nil
+	}
+	return 0, evalErrorf(f, "unsupported function")
+}
+
+// Counts values matching operator and pattern of count(/host/key,period,operator,pattern).
+func (e *Evaluator) count(f *ExprFunction, values []float64) (float64, error) {
+	operator, pattern := f.Param(1), f.Param(2)
+	if pattern == "" && operator == "" {
+		return float64(len(values)), nil
+	}
+	if operator == "" {
+		operator = "eq"
+	}
+
+	var re *regexp.Regexp
+	var p float64
+	var err error
+	switch operator {
+	case "regexp", "iregexp":
+		if operator == "iregexp" {
+			pattern = "(?i)" + pattern
+		}
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			return 0, evalErrorf(f, "%s", err)
+		}
+	case "like":
+	default:
+		p, err = parseEvalNumber(f, pattern)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := 0
+	for _, v := range values {
+		var match bool
+		switch operator {
+		case "eq":
+			match = math.Abs(v-p) <= evalEpsilon
+		case "ne":
+			match = math.Abs(v-p) > evalEpsilon
+		case "gt":
+			match = v > p+evalEpsilon
+		case "ge":
+			match = v >= p-evalEpsilon
+		case "lt":
+			match = v < p-evalEpsilon
+		case "le":
+			match = v <= p+evalEpsilon
+		case "band":
+			match = uint64(v)&uint64(p) == uint64(p)
+		case "like":
+			match = strings.Contains(strconv.FormatFloat(v, 'f', -1, 64), pattern)
+		case "regexp", "iregexp":
+			match = re.MatchString(strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			return 0, evalErrorf(f, "unsupported operator %s", operator)
+		}
+		if match {
+			n++
+		}
+	}
+	return float64(n), nil
+}
@@ -0,0 +1,112 @@
+package zabbix_test
+
+import (
+	. "."
+	"testing"
+	"time"
+)
+
+func testHistory(start time.Time, step time.Duration, values ...float64) (res HistoryItems) {
+	for i, v := range values {
+		res = append(res, HistoryItem{Clock: Timestamp(start.Add(time.Duration(i) * step)), Value: v})
+	}
+	return
+}
+
+func TestEvaluator(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	e := NewEvaluator()
+	e.SetHistory("h", "cpu", testHistory(start, time.Minute, 10, 20, 30, 40, 50))
+	e.Macros["{$MAX}"] = "35"
+	now := start.Add(4 * time.Minute)
+
+	for expression, expected := range map[string]float64{
+		`last(/h/cpu)`:                     50,
+		`{h:cpu.last()}`:                   50,
+		`last(/h/cpu,#2)`:                  40,
+		`{h:cpu.prev()}`:                   40,
+		`avg(/h/cpu,3m)`:                   40,
+		`{h:cpu.avg(#5)}`:                  30,
+		`min(/h/cpu,5m)+max(/h/cpu,5m)`:    60,
+		`sum(/h/cpu,2m:now-1m)`:            70,
+		`count(/h/cpu,5m,"gt",25)`:         3,
+		`{h:cpu.count(5m,20,ge)}`:          4,
+		`change(/h/cpu)`:                   10,
+		`{h:cpu.diff()}`:                   1,
+		`{h:cpu.delta(5m)}`:                40,
+		`nodata(/h/cpu,30s)`:               0,
+		`{h:cpu.nodata(5m)}`:               0,
+		`last(/h/cpu)>{$MAX} and {$MAX}>0`: 1,
+		`{h:cpu.last()}#50 or 2*3=6`:       1,
+		`abs(-{$MAX:"ctx"}/5)`:             7,
+		`not (last(/h/cpu)<1K)`:            0,
+		`last(/h/mem) or last(/h/cpu)`:     1,
+		`last(/h/mem) and 0`:               0,
+	} {
+		v, err := e.EvaluateString(expression, now)
+		if err != nil {
+			t.Errorf("%s: %s", expression, err)
+			continue
+		}
+		if v != expected {
+			t.Errorf("%s: expected %v, got %v", expression, expected, v)
+		}
+	}
+
+	v, err := e.EvaluateString(`nodata(/h/cpu,30s)`, now.Add(time.Minute))
+	if err != nil || v != 1 {
+		t.Errorf("nodata: expected 1, got %v (%v)", v, err)
+	}
+
+	for _, expression := range []string{
+		`last(/h/cpu,#6)`,
+		`last(/h/mem)`,
+		`avg(/h/cpu,10s:now-30s)`,
+		`last(/h/cpu)/0`,
+		`{$UNKNOWN}>1`,
+	} {
+		_, err := e.EvaluateString(expression, now)
+		if _, ok := err.(*EvalError); !ok {
+			t.Errorf("%s: expected evaluation error, got %v", expression, err)
+		}
+	}
+}
+
+func TestEvaluatorSimulate(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	e := NewEvaluator()
+	e.SetHistory("h", "cpu", testHistory(start, time.Minute, 10, 95, 92, 85, 70, 95))
+
+	trigger := &Trigger{
+		Expression:          `last(/h/cpu)>90`,
+		RecoveryMode:        TriggerRecoveryRecoveryExpression,
+		Recovery_expression: `last(/h/cpu)<80`,
+	}
+	events, err := e.Simulate(trigger, start, start.Add(time.Hour), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []TriggerEvent{
+		{start.Add(1 * time.Minute), 1},
+		{start.Add(4 * time.Minute), 0},
+		{start.Add(5 * time.Minute), 1},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, events)
+	}
+	for i := range events {
+		if !events[i].Time.Equal(expected[i].Time) || events[i].Value != expected[i].Value {
+			t.Errorf("Expected %v, got %v", expected[i], events[i])
+		}
+	}
+
+	// hysteresis with {TRIGGER.VALUE} in legacy syntax
+	trigger = &Trigger{Expression: `({TRIGGER.VALUE}=0 and {h:cpu.last()}>90) or ({TRIGGER.VALUE}=1 and {h:cpu.last()}>80)`}
+	events, err = e.Simulate(trigger, start, start.Add(time.Hour), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || !events[1].Time.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("Bad events: %v", events)
+	}
+}
@@ -0,0 +1,152 @@
+package zabbix_test
+
+import (
+	. "."
+	"testing"
+)
+
+func TestExpressionRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		`{host:system.cpu.load[all,avg1].last()}>5`,
+		`{host:agent.ping.nodata(5m)}=1`,
+		`{host:vfs.fs.size[/,pfree].last(0)}<{$FS_MIN:"/"} and {host:vfs.fs.size[/,pfree].avg(1h)}<20`,
+		`{host:log[/var/log/app.log,"a,b]"].str("error")}=1 or not {host:k.diff()}#0`,
+		`({Template OS:proc.num[,,run].last()}>{$MAX}) and {TRIGGER.VALUE}=0`,
+		`{13055}>0 or {13056}<-1`,
+		`last(/host/system.cpu.load[all,avg1])>5`,
+		`avg(/host/net.if.in["eth0"],5m)>1G and count(/host/log.key,#10,"regexp","error")>0`,
+		`min(/host/k,1h:now-1d)*2+-1>=last(//k)/4`,
+		`abs(last(/host/k)-last(/host/k,#2))>10`,
+		`avg(last_foreach(/*/vfs.fs.size[*,pused]?[group="Linux" and tag="fs"]))>90`,
+		`nodata(/host/agent.ping,5m)=1`,
+	} {
+		node, err := ParseExpression(s)
+		if err != nil {
+			t.Errorf("%s: %s", s, err)
+			continue
+		}
+		if node.String() != s {
+			t.Errorf("Round trip failed:\n%s\n%s", s, node.String())
+		}
+	}
+}
+
+func TestExpressionTree(t *testing.T) {
+	node, err := ParseExpression(`{h:k[a].last()} > 1 + 2 * 3 and {$M} = 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	and, ok := node.(*ExprBinary)
+	if !ok || and.Op != "and" {
+		t.Fatalf("Expected and: %#v", node)
+	}
+	gt := and.Left.(*ExprBinary)
+	if gt.Op != ">" {
+		t.Errorf("Expected >: %#v", gt)
+	}
+	plus := gt.Right.(*ExprBinary)
+	if plus.Op != "+" || plus.Right.(*ExprBinary).Op != "*" {
+		t.Errorf("Bad precedence: %s", plus)
+	}
+	f := gt.Left.(*ExprFunction)
+	if f.Name != "last" || f.Item.Host != "h" || f.Item.Key != "k[a]" || !f.Legacy {
+		t.Errorf("Bad function: %#v", f)
+	}
+
+	refs := ExpressionItemRefs(node)
+	if len(refs) != 1 {
+		t.Errorf("Bad refs: %#v", refs)
+	}
+	missing := ExpressionMissingItems(node, func(host, key string) bool { return false })
+	if len(missing) != 1 || missing[0].Key != "k[a]" {
+		t.Errorf("Bad missing items: %#v", missing)
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	for _, s := range []string{
+		``,
+		`{host:key.last()`,
+		`{host:key}>0`,
+		`last(/host/k[a>0`,
+		`(1>0`,
+		`1>0)`,
+		`"abc`,
+	} {
+		_, err := ParseExpression(s)
+		if _, ok := err.(*ExpressionError); !ok {
+			t.Errorf("%s: expected error, got %v", s, err)
+		}
+	}
+}
+
+func TestExpressionConvert(t *testing.T) {
+	for legacy, expected := range map[string]string{
+		`{h:k.last()}>5`:                                 `last(/h/k)>5`,
+		`{h:k.last(0)}#0`:                                `last(/h/k)<>0`,
+		`{h:k.last(#3)}=1`:                               `last(/h/k,#3)=1`,
+		`{h:k.last(,1d)}=1`:                              `last(/h/k,#1:now-1d)=1`,
+		`{h:k[a,"b c"].avg(300)}>{$MAX}`:                 `avg(/h/k[a,"b c"],300s)>{$MAX}`,
+		`{h:k.min(1h,1d)}<1`:                             `min(/h/k,1h:now-1d)<1`,
+		`{h:k.count(5m,error,like)}>0`:                   `count(/h/k,5m,"like","error")>0`,
+		`{h:k.count(#10,5)}>0`:                           `count(/h/k,#10,"eq","5")>0`,
+		`{h:k.diff()}=1`:                                 `(last(/h/k,#1)<>last(/h/k,#2))=1`,
+		`{h:k.abschange()}>10 and {h:k.nodata(5m)}=1`:    `abs(change(/h/k))>10 and nodata(/h/k,5m)=1`,
+		`{h:k.str(fail)}=1 or {h:k.regexp("^E",#3)}=1`:   `find(/h/k,,"like","fail")=1 or find(/h/k,#3,"regexp","^E")=1`,
+		`{h:k.strlen()}>0 and {h:k.now()}>0`:             `length(last(/h/k))>0 and now()>0`,
+		`{h:k.band(,12)}=8`:                              `bitand(last(/h/k),12)=8`,
+		`{h:k.delta(1h)}>100`:                            `(max(/h/k,1h)-min(/h/k,1h))>100`,
+		`{h:k.prev()}<>{h:k.last()}`:                     `last(/h/k,#2)<>last(/h/k)`,
+		`{h:k.logseverity()}>3 and {h:k.logsource(x)}=1`: `logseverity(/h/k)>3 and logsource(/h/k,,"x")=1`,
+		`{h:k.nodata(5m,strict)}=1`:                      `nodata(/h/k,5m,"strict")=1`,
+	} {
+		res, err := ConvertExpression(legacy, NewSyntax)
+		if err != nil {
+			t.Errorf("%s: %s", legacy, err)
+		}
+		if res != expected {
+			t.Errorf("Bad conversion of %s:\n%s\n%s", legacy, expected, res)
+		}
+	}
+
+	for expression, expected := range map[string]string{
+		`last(/h/k)>5`:                         `{h:k.last()}>5`,
+		`avg(/h/k,1h:now-1d)>1`:                `{h:k.avg(1h,1d)}>1`,
+		`count(/h/k,5m,"like","error")>0`:      `{h:k.count(5m,"error","like")}>0`,
+		`find(/h/k,#3,"regexp","^E")=1`:        `{h:k.regexp("^E",#3)}=1`,
+		`abs(change(/h/k))>1 and now()>0`:      `{h:k.abschange()}>1 and {h:k.now()}>0`,
+		`bitand(last(/h/k,#2),12)=8`:           `{h:k.band(#2,12)}=8`,
+		`length(last(/h/k))>0`:                 `{h:k.strlen()}>0`,
+		`(last(/h/k,#1)<>last(/h/k,#2))=1`:     `({h:k.last(#1)}<>{h:k.last(#2)})=1`,
+		`{h:k.last()}>0 and nodata(/h/k,5m)=1`: `{h:k.last()}>0 and {h:k.nodata(5m)}=1`,
+		`nodata(/h/k,5m,"strict")=1`:           `{h:k.nodata(5m,strict)}=1`,
+	} {
+		res, err := ConvertExpression(expression, LegacySyntax)
+		if err != nil {
+			t.Errorf("%s: %s", expression, err)
+		}
+		if res != expected {
+			t.Errorf("Bad conversion of %s:\n%s\n%s", expression, expected, res)
+		}
+	}
+
+	res, err := ConvertExpression(`{h:k.fuzzytime(60,1)}=1`, NewSyntax)
+	if e, ok := err.(*ConversionError); !ok || len(e.Problems) != 1 || res != `{h:k.fuzzytime(60,1)}=1` {
+		t.Errorf("Expected conversion error for fuzzytime() with extra parameter, got %s (%v)", res, err)
+	}
+
+	for _, expression := range []string{
+		`avg(/h/k,1d:now/d)>1`,
+		`last(//k)>1`,
+		`now()>0`,
+		`trendavg(/h/k,1M:now/M)>1`,
+	} {
+		res, err := ConvertExpression(expression, LegacySyntax)
+		if e, ok := err.(*ConversionError); !ok || len(e.Problems) != 1 {
+			t.Errorf("%s: expected conversion error, got %v", expression, err)
+		}
+		if res != expression {
+			t.Errorf("Expression changed: %s -> %s", expression, res)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// graph prototypes
+
+package zabbix
+
+import (
+	"github.com/wOvAN/reflector"
+)
+
+type GraphType int
+
+const (
+	GraphNormal   GraphType = 0
+	GraphStacked  GraphType = 1
+	GraphPie      GraphType = 2
+	GraphExploded GraphType = 3
+)
+
+// https://www.zabbix.com/documentation/5.0/manual/api/reference/graphitem/object
+type GraphItem struct {
+	GItemId   string `json:"gitemid,omitempty"`
+	ItemId    string `json:"itemid"`
+	Color     string `json:"color"`
+	DrawType  int    `json:"drawtype,omitempty"`
+	SortOrder int    `json:"sortorder,omitempty"`
+	YAxisSide int    `json:"yaxisside,omitempty"`
+	CalcFnc   int    `json:"calc_fnc,omitempty"`
+	Type      int    `json:"type,omitempty"`
+}
+
+type GraphItems []GraphItem
+
+// https://www.zabbix.com/documentation/5.0/manual/api/reference/graphprototype/object
+type GraphPrototype struct {
+	GraphId    string    `json:"graphid,omitempty"`
+	Name       string    `json:"name"`
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	GraphType  GraphType `json:"graphtype,omitempty"`
+	ShowLegend int       `json:"show_legend,omitempty"`
+	TemplateId string    `json:"templateid,omitempty"`
+
+	// Returned by graphprototype.get only with selectGraphItems, required for graphprototype.create
+	GraphItems GraphItems `json:"gitems,omitempty"`
+}
+
+type GraphPrototypes []GraphPrototype
+
+// Returns ids of all graph prototypes.
+func (prototypes GraphPrototypes) Ids() (res []string) {
+	res = make([]string, len(prototypes))
+	for i, prototype := range prototypes {
+		res[i] = prototype.GraphId
+	}
+	return
+}
+
+// Wrapper for graphprototype.get: https://www.zabbix.com/documentation/5.0/manual/api/reference/graphprototype/get
+func (api *API) GraphPrototypesGet(params Params) (res GraphPrototypes, err error) {
+	if _, present := params["output"]; !present {
+		params["output"] = "extend"
+	}
+	response, err := api.CallWithError("graphprototype.get", params)
+	if err != nil {
+		return
+	}
+
+	reflector.MapsToStructs2(response.Result.([]interface{}), &res, reflector.Strconv, "json")
+	return
+}
+
+// Gets graph prototypes of discovery rule with graph items.
+func (api *API) GraphPrototypesGetByRuleId(ruleId string) (res GraphPrototypes, err error) {
+	return api.GraphPrototypesGet(Params{"discoveryids": ruleId, "selectGraphItems": "extend"})
+}
+
+// Wrapper for graphprototype.create: https://www.zabbix.com/documentation/5.0/manual/api/reference/graphprototype/create
+func (api *API) GraphPrototypesCreate(prototypes GraphPrototypes) (err error) {
+	response, err := api.CallWithError("graphprototype.create", prototypes)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	graphids := result["graphids"].([]interface{})
+	for i, id := range graphids {
+		prototypes[i].GraphId = id.(string)
+	}
+	return
+}
+
+// Wrapper for graphprototype.update: https://www.zabbix.com/documentation/5.0/manual/api/reference/graphprototype/update
+func (api *API) GraphPrototypesUpdate(prototypes GraphPrototypes) (err error) {
+	response, err := api.CallWithError("graphprototype.update", prototypes)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	graphids := result["graphids"].([]interface{})
+	if len(prototypes) != len(graphids) {
+		err = &ExpectedMore{len(prototypes), len(graphids)}
+	}
+	return
+}
+
+// Wrapper for graphprototype.delete: https://www.zabbix.com/documentation/5.0/manual/api/reference/graphprototype/delete
+// Cleans GraphId in all prototypes elements if call succeed.
+func (api *API) GraphPrototypesDelete(prototypes GraphPrototypes) (err error) {
+	err = api.GraphPrototypesDeleteByIds(prototypes.Ids())
+	if err == nil {
+		for i := range prototypes {
+			prototypes[i].GraphId = ""
+		}
+	}
+	return
+}
+
+// Wrapper for graphprototype.delete: https://www.zabbix.com/documentation/5.0/manual/api/reference/graphprototype/delete
+func (api *API) GraphPrototypesDeleteByIds(ids []string) (err error) {
+	response, err := api.CallWithError("graphprototype.delete", ids)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	graphids := result["graphids"].([]interface{})
+	if len(ids) != len(graphids) {
+		err = &ExpectedMore{len(ids), len(graphids)}
+	}
+	return
+}
@@ -28,16 +28,146 @@ func (t *Timestamp) String() string {
 	return time.Time(*t).String()
 }
 
+// Value of numeric float item, also returned by HistoryGet.
 type HistoryItem struct {
 	ItemId string    `json:"itemid"`
 	Clock  Timestamp `json:"clock"`
-	Value  float32   `json:"value"`
+	Value  float64   `json:"value"`
 	Ns     int       `json:"ns"`
 }
 
 type HistoryItems []HistoryItem
 
+// Returns time of value with nanoseconds.
+func (h *HistoryItem) Time() time.Time {
+	return historyTime(h.Clock, h.Ns)
+}
+
+// Value of numeric unsigned item.
+type HistoryUintItem struct {
+	ItemId string    `json:"itemid"`
+	Clock  Timestamp `json:"clock"`
+	Value  uint64    `json:"value"`
+	Ns     int       `json:"ns"`
+}
+
+type HistoryUintItems []HistoryUintItem
+
+// Returns time of value with nanoseconds.
+func (h *HistoryUintItem) Time() time.Time {
+	return historyTime(h.Clock, h.Ns)
+}
+
+// Value of character or text item.
+type HistoryTextItem struct {
+	ItemId string    `json:"itemid"`
+	Clock  Timestamp `json:"clock"`
+	Value  string    `json:"value"`
+	Ns     int       `json:"ns"`
+}
+
+type HistoryTextItems []HistoryTextItem
+
+// Returns time of value with nanoseconds.
+func (h *HistoryTextItem) Time() time.Time {
+	return historyTime(h.Clock, h.Ns)
+}
+
+// Value of log item. Timestamp is time of log entry, Clock is time when it was received.
+type HistoryLogItem struct {
+	ItemId     string    `json:"itemid"`
+	Clock      Timestamp `json:"clock"`
+	Value      string    `json:"value"`
+	Ns         int       `json:"ns"`
+	Timestamp  Timestamp `json:"timestamp"`
+	Source     string    `json:"source"`
+	Severity   int       `json:"severity"`
+	LogEventId int       `json:"logeventid"`
+}
+
+type HistoryLogItems []HistoryLogItem
+
+// Returns time of value with nanoseconds.
+func (h *HistoryLogItem) Time() time.Time {
+	return historyTime(h.Clock, h.Ns)
+}
+
+func historyTime(clock Timestamp, ns int) time.Time {
+	return time.Time(clock).Add(time.Duration(ns))
+}
+
+// History of single item, only field matching ValueType is filled.
+type History struct {
+	ValueType ValueType
+	Float     HistoryItems
+	Uint      HistoryUintItems
+	Text      HistoryTextItems // character and text items
+	Log       HistoryLogItems
+}
+
+// Returns number of values.
+func (h *History) Len() int {
+	return len(h.Float) + len(h.Uint) + len(h.Text) + len(h.Log)
+}
+
+// Wrapper for history.get: https://www.zabbix.com/documentation/4.0/manual/api/reference/history/get
+// Returns float values unless history is set in params, use typed variants for other value types.
 func (api *API) HistoryGet(params Params) (res HistoryItems, err error) {
+	err = api.historyGet(params, 0, &res)
+	return
+}
+
+// Gets history of unsigned items.
+func (api *API) HistoryGetUint(params Params) (res HistoryUintItems, err error) {
+	err = api.historyGet(params, 3, &res)
+	return
+}
+
+// Gets history of text items, set history to 1 in params for character items.
+func (api *API) HistoryGetText(params Params) (res HistoryTextItems, err error) {
+	err = api.historyGet(params, 4, &res)
+	return
+}
+
+// Gets history of log items.
+func (api *API) HistoryGetLog(params Params) (res HistoryLogItems, err error) {
+	err = api.historyGet(params, 2, &res)
+	return
+}
+
+// Gets history of item selecting history parameter and result type from item ValueType.
+// Other params like time_from or limit are passed as is.
+func (api *API) HistoryGetByItem(item *Item, params Params) (res *History, err error) {
+	valueType, err := strconv.Atoi(fmt.Sprint(item.ValueType))
+	if err != nil {
+		return nil, fmt.Errorf("Item %s: invalid value type %v", item.Key, item.ValueType)
+	}
+	if params == nil {
+		params = Params{}
+	}
+	params["itemids"] = item.ItemId
+	params["history"] = valueType
+
+	res = &History{ValueType: item.ValueType}
+	switch valueType {
+	case 0:
+		err = api.historyGet(params, valueType, &res.Float)
+	case 1, 4:
+		err = api.historyGet(params, valueType, &res.Text)
+	case 2:
+		err = api.historyGet(params, valueType, &res.Log)
+	case 3:
+		err = api.historyGet(params, valueType, &res.Uint)
+	default:
+		return nil, fmt.Errorf("Item %s: unknown value type %d", item.Key, valueType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (api *API) historyGet(params Params, history int, res interface{}) (err error) {
 	if _, present := params["output"]; !present {
 		params["output"] = "extend"
 	}
@@ -45,14 +175,69 @@ func (api *API) HistoryGet(params Params) (res HistoryItems, err error) {
 		params["limit"] = "100"
 	}
 	if _, presenth := params["history"]; !presenth {
-		params["history"] = "0"
+		params["history"] = history
 	}
 	response, err := api.CallWithError("history.get", params)
 	if err != nil {
 		return
 	}
-	//fmt.Println(response.Result)
 
-	reflector.MapsToStructs2(response.Result.([]interface{}), &res, reflector.Strconv, "json")
+	reflector.MapsToStructs2(response.Result.([]interface{}), res, reflector.Strconv, "json")
+	return
+}
+
+// Value for history.push, either ItemId or Host and Key should be set. Zero Clock means time of receiving.
+type HistoryPushValue struct {
+	ItemId string      `json:"itemid,omitempty"`
+	Host   string      `json:"host,omitempty"`
+	Key    string      `json:"key,omitempty"`
+	Value  interface{} `json:"value"`
+	Clock  int64       `json:"clock,omitempty"`
+	Ns     int         `json:"ns,omitempty"`
+}
+
+// Result for single pushed value, Error is empty on success.
+type HistoryPushResult struct {
+	ItemId string `json:"itemid"`
+	Error  string `json:"error"`
+}
+
+type HistoryPushResults []HistoryPushResult
+
+// Returns number of failed values.
+func (results HistoryPushResults) Failed() (n int) {
+	for _, r := range results {
+		if r.Error != "" {
+			n++
+		}
+	}
+	return
+}
+
+// Maximum number of values sent in a single history.push call.
+var HistoryPushBatchSize = 1000
+
+// Wrapper for history.push: https://www.zabbix.com/documentation/7.0/manual/api/reference/history/push
+// Values are sent in batches of HistoryPushBatchSize, results are in the same order as values.
+func (api *API) HistoryPush(values []HistoryPushValue) (res HistoryPushResults, err error) {
+	for start := 0; start < len(values); start += HistoryPushBatchSize {
+		end := start + HistoryPushBatchSize
+		if end > len(values) {
+			end = len(values)
+		}
+		response, err := api.CallWithError("history.push", values[start:end])
+		if err != nil {
+			return res, err
+		}
+
+		result, _ := response.Result.(map[string]interface{})
+		data, _ := result["data"].([]interface{})
+		if len(data) != end-start {
+			return res, &ExpectedMore{end - start, len(data)}
+		}
+		var batch HistoryPushResults
+		reflector.MapsToStructs2(data, &batch, reflector.Strconv, "json")
+		res = append(res, batch...)
+	}
 	return
 }
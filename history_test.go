@@ -0,0 +1,101 @@
+package zabbix_test
+
+import (
+	. "."
+	"testing"
+	"time"
+)
+
+func TestHistoryTime(t *testing.T) {
+	clock := time.Unix(1500000000, 0)
+	h := HistoryUintItem{Clock: Timestamp(clock), Ns: 250, Value: 18446744073709551615}
+	if !h.Time().Equal(clock.Add(250 * time.Nanosecond)) {
+		t.Errorf("Bad time %v", h.Time())
+	}
+}
+
+// Returns API answering history.get with given values, encoded as strings like Zabbix does.
+func stubHistoryAPI(t *testing.T, history int, values ...map[string]interface{}) *API {
+	return stubAPI(func(method string, params interface{}) interface{} {
+		if p := params.(map[string]interface{}); method != "history.get" || p["history"] != float64(history) {
+			t.Errorf("Unexpected call %s %v", method, params)
+		}
+		res := make([]interface{}, len(values))
+		for i, v := range values {
+			res[i] = v
+		}
+		return res
+	})
+}
+
+func TestHistoryDecode(t *testing.T) {
+	clock := time.Unix(1500000000, 0)
+
+	uints, err := stubHistoryAPI(t, 3, map[string]interface{}{
+		"itemid": "23296", "clock": "1500000000", "value": "18446744073709551615", "ns": "999999999",
+	}).HistoryGetUint(Params{"itemids": "23296"})
+	if err != nil || len(uints) != 1 {
+		t.Fatal(uints, err)
+	}
+	if uints[0].Value != 18446744073709551615 || uints[0].ItemId != "23296" {
+		t.Errorf("Bad value above 2^53 %#v", uints[0])
+	}
+	if !uints[0].Time().Equal(clock.Add(999999999 * time.Nanosecond)) {
+		t.Errorf("Bad time %v", uints[0].Time())
+	}
+
+	floats, err := stubHistoryAPI(t, 0, map[string]interface{}{
+		"itemid": "23297", "clock": "1500000000", "value": "0.0001", "ns": "1",
+	}).HistoryGet(Params{"itemids": "23297"})
+	if err != nil || len(floats) != 1 || floats[0].Value != 0.0001 || floats[0].Time().Sub(clock) != time.Nanosecond {
+		t.Errorf("Bad float values %#v (%v)", floats, err)
+	}
+
+	logs, err := stubHistoryAPI(t, 2, map[string]interface{}{
+		"itemid": "23298", "clock": "1500000005", "value": "Service started", "ns": "500",
+		"timestamp": "1500000000", "source": "Service Control Manager", "severity": "4", "logeventid": "7036",
+	}).HistoryGetLog(Params{"itemids": "23298"})
+	if err != nil || len(logs) != 1 {
+		t.Fatal(logs, err)
+	}
+	l := logs[0]
+	if l.Value != "Service started" || l.Source != "Service Control Manager" || l.Severity != 4 || l.LogEventId != 7036 {
+		t.Errorf("Bad log value %#v", l)
+	}
+	if !time.Time(l.Timestamp).Equal(clock) || !l.Time().Equal(clock.Add(5*time.Second+500*time.Nanosecond)) {
+		t.Errorf("Bad log times %v and %v", time.Time(l.Timestamp), l.Time())
+	}
+}
+
+func TestHistory(t *testing.T) {
+	api := getAPI(t)
+
+	group := CreateHostGroup(t)
+	defer DeleteHostGroup(group, t)
+
+	host := CreateHost(group, t)
+	defer DeleteHost(host, t)
+
+	app := CreateApplication(host, t)
+	defer DeleteApplication(app, t)
+
+	item := CreateItem(app, t)
+	defer DeleteItem(item, t)
+
+	items, err := api.ItemsGet(Params{"itemids": item.ItemId})
+	if err != nil || len(items) != 1 {
+		t.Fatal(items, err)
+	}
+	history, err := api.HistoryGetByItem(&items[0], Params{"time_from": time.Now().Add(-time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history.Len() != 0 {
+		t.Errorf("Expected no history for new item, got %#v", history)
+	}
+
+	_, err = api.HistoryGetByItem(&Item{Key: "bad", ValueType: "x"}, nil)
+	if err == nil {
+		t.Error("Expected error for invalid value type")
+	}
+}
@@ -1,9 +1,17 @@
 package zabbix
 
 import (
+	"fmt"
+
 	"github.com/wOvAN/reflector"
 )
 
+// Host selectors, see also template selectors
+const (
+	SelectInterfaces = "selectInterfaces"
+	SelectInventory  = "selectInventory"
+)
+
 type (
 	AvailableType int
 	StatusType    int
@@ -26,19 +34,51 @@ type Host struct {
 	Name      string        `json:"name"`
 	Status    StatusType    `json:"status"`
 
+	Description string `json:"description,omitempty"`
+
+	// IPMI and encryption settings
+	IPMIAuthType   string `json:"ipmi_authtype,omitempty"`
+	IPMIPrivilege  string `json:"ipmi_privilege,omitempty"`
+	IPMIUsername   string `json:"ipmi_username,omitempty"`
+	IPMIPassword   string `json:"ipmi_password,omitempty"`
+	TLSConnect     string `json:"tls_connect,omitempty"`
+	TLSAccept      string `json:"tls_accept,omitempty"`
+	TLSIssuer      string `json:"tls_issuer,omitempty"`
+	TLSSubject     string `json:"tls_subject,omitempty"`
+	TLSPSKIdentity string `json:"tls_psk_identity,omitempty"`
+	TLSPSK         string `json:"tls_psk,omitempty"`
+
 	// Fields below used only when creating hosts
 	GroupIds    HostGroupIds   `json:"groups,omitempty"`
 	Interfaces  HostInterfaces `json:"interfaces,omitempty"`
 	Templates   TemplateIds    `json:"templates,omitempty"`
 	ProxyHostID string         `json:"proxy_hostid,omitempty"`
-	//proxy_hostid 	string 	ID of the proxy that is used to monitor the host.
-	//TODO:
-	//Macros   Macroses      `json:"macros,omitempty"`
-	//Inventory Inventories `json:"inventory,omitempty"`
+
+	// Templates linked directly to host, returned with SelectParentTemplates
+	ParentTemplates Templates `json:"parentTemplates,omitempty"`
+
+	// Returned with SelectMacros and SelectInventory. Inventory is object of inventory fields,
+	// or empty array if inventory is disabled.
+	Macros    HostMacros  `json:"macros,omitempty"`
+	Inventory interface{} `json:"inventory,omitempty"`
 }
 
 type Hosts []Host
 
+type HostId struct {
+	HostId string `json:"hostid"`
+}
+
+type HostIds []HostId
+
+// Returns host ids for given ids.
+func NewHostIds(ids ...string) (res HostIds) {
+	for _, id := range ids {
+		res = append(res, HostId{HostId: id})
+	}
+	return
+}
+
 // Wrapper for host.get: https://www.zabbix.com/documentation/3.2/manual/api/reference/host/get
 func (api *API) HostsGet(params Params) (res Hosts, err error) {
 	if _, present := params["output"]; !present {
@@ -156,3 +196,136 @@ func (api *API) HostsDeleteByIds(ids []string) (err error) {
 	}
 	return
 }
+
+// Returns templates inherited by hosts directly or through other templates:
+// host id to template id to id of template linked directly to host, which brings it.
+func (api *API) hostsTemplateSources(hostIds []string) (res map[string]map[string]string, err error) {
+	hosts, err := api.HostsGet(Params{
+		"hostids":             hostIds,
+		"output":              []string{"hostid", "host"},
+		SelectParentTemplates: []string{"templateid"},
+	})
+	if err != nil {
+		return
+	}
+
+	var direct []string
+	for _, h := range hosts {
+		for _, t := range h.ParentTemplates {
+			direct = append(direct, t.TemplateId)
+		}
+	}
+	parents, err := api.templateParents(direct)
+	if err != nil {
+		return
+	}
+
+	res = make(map[string]map[string]string, len(hosts))
+	for _, h := range hosts {
+		sources := make(map[string]string)
+		// direct links win over inherited ones
+		for _, t := range h.ParentTemplates {
+			sources[t.TemplateId] = t.TemplateId
+		}
+		for _, t := range h.ParentTemplates {
+			stack := append([]string{}, parents[t.TemplateId]...)
+			for len(stack) > 0 {
+				id := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if _, seen := sources[id]; seen {
+					continue
+				}
+				sources[id] = t.TemplateId
+				stack = append(stack, parents[id]...)
+			}
+		}
+		res[h.HostId] = sources
+	}
+	return
+}
+
+// Returns templates linked to given templates and all their ancestors: template id to ids of linked templates.
+func (api *API) templateParents(ids []string) (res map[string][]string, err error) {
+	res = make(map[string][]string)
+	for len(ids) > 0 {
+		var templates Templates
+		templates, err = api.TemplatesGet(Params{
+			"templateids":         ids,
+			"output":              []string{"templateid"},
+			SelectParentTemplates: []string{"templateid"},
+		})
+		if err != nil {
+			return
+		}
+		ids = nil
+		for _, t := range templates {
+			res[t.TemplateId] = []string{}
+			for _, p := range t.ParentTemplates {
+				res[t.TemplateId] = append(res[t.TemplateId], p.TemplateId)
+			}
+		}
+		for _, t := range templates {
+			for _, p := range t.ParentTemplates {
+				if _, present := res[p.TemplateId]; !present {
+					res[p.TemplateId] = nil
+					ids = append(ids, p.TemplateId)
+				}
+			}
+		}
+	}
+	return
+}
+
+// Links templates to hosts using host.massadd. Templates which hosts already inherit,
+// directly or through other templates, are skipped, since Zabbix refuses to link them twice.
+func (api *API) HostsLinkTemplates(hostIds []string, templateIds []string) (err error) {
+	sources, err := api.hostsTemplateSources(hostIds)
+	if err != nil {
+		return
+	}
+	for _, hostId := range hostIds {
+		var missing []string
+		for _, id := range templateIds {
+			if _, present := sources[hostId][id]; !present {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		_, err = api.CallWithError("host.massadd", Params{"hosts": NewHostIds(hostId), "templates": NewTemplateIds(missing...)})
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Unlinks templates from hosts keeping inherited items, triggers and other entities.
+// See HostsUnlinkAndClearTemplates.
+func (api *API) HostsUnlinkTemplates(hostIds []string, templateIds []string) (err error) {
+	return api.hostsUnlinkTemplates(hostIds, templateIds, "templateids")
+}
+
+// Unlinks templates from hosts and deletes inherited entities, including ones from templates nested in them.
+// Templates must be linked to hosts directly, error is returned for template inherited through other one.
+func (api *API) HostsUnlinkAndClearTemplates(hostIds []string, templateIds []string) (err error) {
+	return api.hostsUnlinkTemplates(hostIds, templateIds, "templateids_clear")
+}
+
+func (api *API) hostsUnlinkTemplates(hostIds []string, templateIds []string, param string) (err error) {
+	sources, err := api.hostsTemplateSources(hostIds)
+	if err != nil {
+		return
+	}
+	for _, hostId := range hostIds {
+		for _, id := range templateIds {
+			if source, present := sources[hostId][id]; present && source != id {
+				return fmt.Errorf("Template %s is linked to host %s through template %s, unlink it instead", id, hostId, source)
+			}
+		}
+	}
+
+	_, err = api.CallWithError("host.massremove", Params{"hostids": hostIds, param: templateIds})
+	return
+}
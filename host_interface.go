@@ -13,13 +13,14 @@ const (
 
 // https://www.zabbix.com/documentation/3.2/manual/appendix/api/hostinterface/definitions
 type HostInterface struct {
-	DNS   string        `json:"dns"`
-	IP    string        `json:"ip"`
-	Main  int           `json:"main"`
-	Port  string        `json:"port"`
-	Type  InterfaceType `json:"type"`
-	UseIP int           `json:"useip"`
-	Bulk  int           `json:"bulk,omitempty"`
+	InterfaceId string        `json:"interfaceid,omitempty"`
+	DNS         string        `json:"dns"`
+	IP          string        `json:"ip"`
+	Main        int           `json:"main"`
+	Port        string        `json:"port"`
+	Type        InterfaceType `json:"type"`
+	UseIP       int           `json:"useip"`
+	Bulk        int           `json:"bulk,omitempty"`
 }
 
 type HostInterfaces []HostInterface
@@ -0,0 +1,22 @@
+package zabbix
+
+// https://www.zabbix.com/documentation/4.0/manual/api/reference/usermacro/object#host_macro
+type HostMacro struct {
+	HostMacroId string `json:"hostmacroid,omitempty"`
+	HostId      string `json:"hostid,omitempty"`
+	Macro       string `json:"macro"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"` // Zabbix 4.4+
+}
+
+type HostMacros []HostMacro
+
+// Returns macros without ids, as expected by host.create and template.create.
+func (macros HostMacros) WithoutIds() (res HostMacros) {
+	for _, m := range macros {
+		m.HostMacroId = ""
+		m.HostId = ""
+		res = append(res, m)
+	}
+	return
+}
@@ -0,0 +1,123 @@
+// host prototypes
+
+package zabbix
+
+import (
+	"github.com/wOvAN/reflector"
+)
+
+// https://www.zabbix.com/documentation/5.0/manual/api/reference/hostprototype/object#group_prototype
+type GroupPrototype struct {
+	Name string `json:"name"`
+}
+
+type GroupPrototypes []GroupPrototype
+
+// https://www.zabbix.com/documentation/5.0/manual/api/reference/hostprototype/object
+type HostPrototype struct {
+	HostId     string     `json:"hostid,omitempty"`
+	Host       string     `json:"host"`
+	Name       string     `json:"name,omitempty"`
+	Status     StatusType `json:"status"`
+	TemplateId string     `json:"templateid,omitempty"`
+
+	// Returned by hostprototype.get only with selectGroupLinks, selectGroupPrototypes and selectTemplates
+	GroupLinks      HostGroupIds    `json:"groupLinks,omitempty"`
+	GroupPrototypes GroupPrototypes `json:"groupPrototypes,omitempty"`
+	Templates       TemplateIds     `json:"templates,omitempty"`
+
+	// Fields below used only when creating host prototypes
+	RuleId string `json:"ruleid,omitempty"`
+}
+
+type HostPrototypes []HostPrototype
+
+// Returns ids of all host prototypes.
+func (prototypes HostPrototypes) Ids() (res []string) {
+	res = make([]string, len(prototypes))
+	for i, prototype := range prototypes {
+		res[i] = prototype.HostId
+	}
+	return
+}
+
+// Wrapper for hostprototype.get: https://www.zabbix.com/documentation/5.0/manual/api/reference/hostprototype/get
+func (api *API) HostPrototypesGet(params Params) (res HostPrototypes, err error) {
+	if _, present := params["output"]; !present {
+		params["output"] = "extend"
+	}
+	response, err := api.CallWithError("hostprototype.get", params)
+	if err != nil {
+		return
+	}
+
+	reflector.MapsToStructs2(response.Result.([]interface{}), &res, reflector.Strconv, "json")
+	return
+}
+
+// Gets host prototypes of discovery rule with group links, group prototypes and templates.
+func (api *API) HostPrototypesGetByRuleId(ruleId string) (res HostPrototypes, err error) {
+	return api.HostPrototypesGet(Params{
+		"discoveryids":          ruleId,
+		"selectGroupLinks":      "extend",
+		"selectGroupPrototypes": "extend",
+		SelectTemplates:         "extend",
+	})
+}
+
+// Wrapper for hostprototype.create: https://www.zabbix.com/documentation/5.0/manual/api/reference/hostprototype/create
+func (api *API) HostPrototypesCreate(prototypes HostPrototypes) (err error) {
+	response, err := api.CallWithError("hostprototype.create", prototypes)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	hostids := result["hostids"].([]interface{})
+	for i, id := range hostids {
+		prototypes[i].HostId = id.(string)
+	}
+	return
+}
+
+// Wrapper for hostprototype.update: https://www.zabbix.com/documentation/5.0/manual/api/reference/hostprototype/update
+func (api *API) HostPrototypesUpdate(prototypes HostPrototypes) (err error) {
+	response, err := api.CallWithError("hostprototype.update", prototypes)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	hostids := result["hostids"].([]interface{})
+	if len(prototypes) != len(hostids) {
+		err = &ExpectedMore{len(prototypes), len(hostids)}
+	}
+	return
+}
+
+// Wrapper for hostprototype.delete: https://www.zabbix.com/documentation/5.0/manual/api/reference/hostprototype/delete
+// Cleans HostId in all prototypes elements if call succeed.
+func (api *API) HostPrototypesDelete(prototypes HostPrototypes) (err error) {
+	err = api.HostPrototypesDeleteByIds(prototypes.Ids())
+	if err == nil {
+		for i := range prototypes {
+			prototypes[i].HostId = ""
+		}
+	}
+	return
+}
+
+// Wrapper for hostprototype.delete: https://www.zabbix.com/documentation/5.0/manual/api/reference/hostprototype/delete
+func (api *API) HostPrototypesDeleteByIds(ids []string) (err error) {
+	response, err := api.CallWithError("hostprototype.delete", ids)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	hostids := result["hostids"].([]interface{})
+	if len(ids) != len(hostids) {
+		err = &ExpectedMore{len(ids), len(hostids)}
+	}
+	return
+}
@@ -0,0 +1,80 @@
+package zabbix
+
+type (
+	HTTPRequestMethod interface{}
+	HTTPPostType      interface{}
+	HTTPRetrieveMode  interface{}
+	HTTPOutputFormat  interface{}
+	HTTPAuthType      interface{}
+	HTTPSwitch        interface{}
+)
+
+var (
+	HTTPGet  HTTPRequestMethod = 0
+	HTTPPost HTTPRequestMethod = 1
+	HTTPPut  HTTPRequestMethod = 2
+	HTTPHead HTTPRequestMethod = 3
+
+	HTTPPostRaw  HTTPPostType = 0
+	HTTPPostJSON HTTPPostType = 2
+	HTTPPostXML  HTTPPostType = 3
+
+	HTTPRetrieveBody    HTTPRetrieveMode = 0
+	HTTPRetrieveHeaders HTTPRetrieveMode = 1
+	HTTPRetrieveBoth    HTTPRetrieveMode = 2
+
+	HTTPOutputRaw  HTTPOutputFormat = 0
+	HTTPOutputJSON HTTPOutputFormat = 1
+
+	HTTPAuthNone     HTTPAuthType = 0
+	HTTPAuthBasic    HTTPAuthType = 1
+	HTTPAuthNTLM     HTTPAuthType = 2
+	HTTPAuthKerberos HTTPAuthType = 3
+
+	// Used for follow_redirects, verify_peer, verify_host and allow_traps.
+	// Fields are interfaces so explicit HTTPOff is sent while nil leaves server default.
+	HTTPOff HTTPSwitch = 0
+	HTTPOn  HTTPSwitch = 1
+)
+
+// Creates item of HTTPAgent type which polls url with GET request every delay and stores response body as text.
+func NewHTTPAgentItem(hostId, key, name, url, delay string) Item {
+	return Item{
+		HostId:      hostId,
+		Key:         key,
+		Name:        name,
+		Type:        HTTPAgent,
+		ValueType:   Text,
+		Delay:       delay,
+		URL:         url,
+		StatusCodes: "200",
+	}
+}
+
+// Appends query field to item URL query, fields are sent in order they were added.
+func (item *Item) AddQueryField(name, value string) {
+	item.QueryFields = append(item.QueryFields, map[string]string{name: value})
+}
+
+// Sets HTTP request header.
+func (item *Item) SetHeader(name, value string) {
+	if item.Headers == nil {
+		item.Headers = make(map[string]string)
+	}
+	item.Headers[name] = value
+}
+
+// Makes item send body as JSON with POST method.
+func (item *Item) SetJSONBody(body string) {
+	item.RequestMethod = HTTPPost
+	item.PostType = HTTPPostJSON
+	item.Posts = body
+	item.SetHeader("Content-Type", "application/json")
+}
+
+// Sets HTTP basic authentication.
+func (item *Item) SetBasicAuth(username, password string) {
+	item.AuthType = HTTPAuthBasic
+	item.Username = username
+	item.Password = password
+}
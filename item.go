@@ -34,6 +34,7 @@ var (
 	JMXAgent          ItemType = 16
 	SNMPTrap          ItemType = 17
 	DependentItem     ItemType = 18
+	HTTPAgent         ItemType = 19
 
 	Float     ValueType = 0
 	Character ValueType = 1
@@ -49,28 +50,109 @@ var (
 	AsIs  DeltaType = 0
 	Speed DeltaType = 1
 	Delta DeltaType = 2
+)
+
+type ItemStatus int
+
+const (
+	ItemEnabled  ItemStatus = 0
+	ItemDisabled ItemStatus = 1
+)
+
+// Maximum number of items sent in a single item.update call by ItemsUpdate and ItemsUpdateByIds.
+// All items are sent in one call if it is not positive.
+var ItemsUpdateBatchSize = 500
 
-	ItemEnabled  = 0
-	ItemDisabled = 1
+// Returns end of batch starting at start.
+func itemsBatchEnd(start, total int) int {
+	end := start + ItemsUpdateBatchSize
+	if ItemsUpdateBatchSize <= 0 || end > total {
+		end = total
+	}
+	return end
+}
+
+const (
+	// Item Selectors
+	SelectPreprocessing = "selectPreprocessing"
 )
 
 // https://www.zabbix.com/documentation/4.0/manual/api/reference/item/object
 type Item struct {
-	ItemId      string      `json:"itemid,omitempty"`
-	Delay       string      `json:"delay"`
-	HostId      string      `json:"hostid"`
-	InterfaceId string      `json:"interfaceid,omitempty"`
-	Key         string      `json:"key_"`
-	Name        string      `json:"name"`
-	Type        ItemType    `json:"type"`
-	ValueType   ValueType   `json:"value_type"`
-	DataType    DataType    `json:"data_type"`
-	Delta       DeltaType   `json:"delta"`
-	Description string      `json:"description"`
-	Error       string      `json:"error"`
-	History     string      `json:"history,omitempty"`
-	Trends      string      `json:"trends,omitempty"`
-	Status      interface{} `json:"status,omitempty"`
+	ItemId      string     `json:"itemid,omitempty"`
+	Delay       string     `json:"delay,omitempty"`
+	HostId      string     `json:"hostid,omitempty"` // Not sent by ItemsUpdate
+	InterfaceId string     `json:"interfaceid,omitempty"`
+	Key         string     `json:"key_"`
+	Name        string     `json:"name"`
+	Type        ItemType   `json:"type"`
+	ValueType   ValueType  `json:"value_type"`
+	DataType    DataType   `json:"data_type,omitempty"` // Removed in Zabbix 3.4
+	Delta       DeltaType  `json:"delta,omitempty"`     // Removed in Zabbix 3.4
+	Description string     `json:"description"`
+	Error       string     `json:"error,omitempty"` // Read-only
+	History     string     `json:"history,omitempty"`
+	Trends      string     `json:"trends,omitempty"`
+	Status      ItemStatus `json:"status"`
+
+	Units         string `json:"units,omitempty"`
+	ValueMapId    string `json:"valuemapid,omitempty"` // "0" if value map is not used
+	LogTimeFmt    string `json:"logtimefmt,omitempty"`
+	InventoryLink string `json:"inventory_link,omitempty"`
+	Formula       string `json:"formula,omitempty"` // Custom multiplier, removed in Zabbix 3.4
+
+	// Used by items of corresponding types: Params is formula of calculated items, SQL query of database monitor
+	// items and script of SSH and TELNET items.
+	Params               string `json:"params,omitempty"`
+	Port                 string `json:"port,omitempty"`
+	SNMPOid              string `json:"snmp_oid,omitempty"`
+	SNMPCommunity        string `json:"snmp_community,omitempty"`
+	SNMPv3ContextName    string `json:"snmpv3_contextname,omitempty"`
+	SNMPv3SecurityName   string `json:"snmpv3_securityname,omitempty"`
+	SNMPv3SecurityLevel  string `json:"snmpv3_securitylevel,omitempty"`
+	SNMPv3AuthProtocol   string `json:"snmpv3_authprotocol,omitempty"`
+	SNMPv3AuthPassphrase string `json:"snmpv3_authpassphrase,omitempty"`
+	SNMPv3PrivProtocol   string `json:"snmpv3_privprotocol,omitempty"`
+	SNMPv3PrivPassphrase string `json:"snmpv3_privpassphrase,omitempty"`
+	IPMISensor           string `json:"ipmi_sensor,omitempty"`
+	JMXEndpoint          string `json:"jmx_endpoint,omitempty"`
+	PublicKey            string `json:"publickey,omitempty"`
+	PrivateKey           string `json:"privatekey,omitempty"`
+
+	// Read-only: id of template item this item is inherited from, "0" for own items.
+	// Not sent by ItemsUpdate.
+	TemplateId string `json:"templateid,omitempty"`
+
+	// Used only by items of DependentItem type
+	MasterItemId string `json:"master_itemid,omitempty"`
+
+	// Used by items of HTTPAgent type, see http_agent.go.
+	// Username and Password are also used by SSH, TELNET, JMX and database monitor items.
+	URL             string              `json:"url,omitempty"`
+	QueryFields     []map[string]string `json:"query_fields,omitempty"`
+	Headers         map[string]string   `json:"headers,omitempty"`
+	RequestMethod   HTTPRequestMethod   `json:"request_method,omitempty"`
+	PostType        HTTPPostType        `json:"post_type,omitempty"`
+	Posts           string              `json:"posts,omitempty"`
+	StatusCodes     string              `json:"status_codes,omitempty"`
+	FollowRedirects HTTPSwitch          `json:"follow_redirects,omitempty"`
+	RetrieveMode    HTTPRetrieveMode    `json:"retrieve_mode,omitempty"`
+	OutputFormat    HTTPOutputFormat    `json:"output_format,omitempty"`
+	AuthType        HTTPAuthType        `json:"authtype,omitempty"`
+	Username        string              `json:"username,omitempty"`
+	Password        string              `json:"password,omitempty"`
+	SSLCertFile     string              `json:"ssl_cert_file,omitempty"`
+	SSLKeyFile      string              `json:"ssl_key_file,omitempty"`
+	SSLKeyPassword  string              `json:"ssl_key_password,omitempty"`
+	VerifyPeer      HTTPSwitch          `json:"verify_peer,omitempty"`
+	VerifyHost      HTTPSwitch          `json:"verify_host,omitempty"`
+	Timeout         string              `json:"timeout,omitempty"`
+	HTTPProxy       string              `json:"http_proxy,omitempty"`
+	AllowTraps      HTTPSwitch          `json:"allow_traps,omitempty"`
+	TrapperHosts    string              `json:"trapper_hosts,omitempty"`
+
+	// Returned by item.get only with SelectPreprocessing
+	Preprocessing PreprocessingSteps `json:"preprocessing,omitempty"`
 
 	// Fields below used only when creating applications
 	ApplicationIds []string `json:"applications,omitempty"`
@@ -121,6 +203,8 @@ func ItemTypeToText(aItemType ItemType) string {
 		return "SNMP trap"
 	case DependentItem:
 		return "Dependent item"
+	case HTTPAgent:
+		return "HTTP agent"
 	default:
 		return "Unknown (" + fmt.Sprintf("%s", aItemType) + ")"
 	}
@@ -141,6 +225,15 @@ func (items Items) ByKey() (res map[string]Item) {
 	return
 }
 
+// Returns ids of all items.
+func (items Items) Ids() (res []string) {
+	res = make([]string, len(items))
+	for i, item := range items {
+		res[i] = item.ItemId
+	}
+	return
+}
+
 // Wrapper for item.get https://www.zabbix.com/documentation/2.2/manual/appendix/api/item/get
 func (api *API) ItemsGet(params Params) (res Items, err error) {
 	if _, present := params["output"]; !present {
@@ -155,13 +248,23 @@ func (api *API) ItemsGet(params Params) (res Items, err error) {
 	return
 }
 
+// Gets items by Ids together with their preprocessing steps.
+func (api *API) ItemsGetWithPreprocessing(ids []string) (res Items, err error) {
+	return api.ItemsGet(Params{"itemids": ids, SelectPreprocessing: "extend"})
+}
+
 // Gets items by application Id.
 func (api *API) ItemsGetByApplicationId(id string) (res Items, err error) {
 	return api.ItemsGet(Params{"applicationids": id})
 }
 
 // Wrapper for item.create: https://www.zabbix.com/documentation/2.2/manual/appendix/api/item/create
+// Items are checked with Validate before sending.
 func (api *API) ItemsCreate(items Items) (err error) {
+	err = items.Validate()
+	if err != nil {
+		return
+	}
 	response, err := api.CallWithError("item.create", items)
 	if err != nil {
 		return
@@ -175,15 +278,125 @@ func (api *API) ItemsCreate(items Items) (err error) {
 	return
 }
 
+// Wrapper for item.update: https://www.zabbix.com/documentation/4.0/manual/api/reference/item/update
+// Items are checked with Validate and sent in batches of ItemsUpdateBatchSize elements.
+// Read-only fields and HostId, which item.update can't change, are not sent.
+// All other fields are sent, including Status, so items must be got by ItemsGet first; items without
+// Key or Name are rejected. Use ItemsUpdateByIds to change only some fields.
+func (api *API) ItemsUpdate(items Items) (err error) {
+	for _, item := range items {
+		if item.Key == "" || item.Name == "" {
+			return fmt.Errorf("Item %s is not complete, use ItemsUpdateByIds to change some fields", item.ItemId)
+		}
+	}
+	err = items.Validate()
+	if err != nil {
+		return
+	}
+	for start, end := 0, 0; start < len(items); start = end {
+		end = itemsBatchEnd(start, len(items))
+		batch := make(Items, end-start)
+		copy(batch, items[start:end])
+		for i := range batch {
+			batch[i].HostId = ""
+			batch[i].Error = ""
+			batch[i].TemplateId = ""
+		}
+		err = api.itemsUpdate(batch, end-start)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Sets fields from params on all items with given ids, for example Params{"delay": "5m", "history": "7d"}.
+// Items are sent in batches of ItemsUpdateBatchSize elements.
+func (api *API) ItemsUpdateByIds(ids []string, params Params) (err error) {
+	for start, end := 0, 0; start < len(ids); start = end {
+		end = itemsBatchEnd(start, len(ids))
+		objects := make([]Params, 0, end-start)
+		for _, id := range ids[start:end] {
+			object := Params{"itemid": id}
+			for k, v := range params {
+				object[k] = v
+			}
+			objects = append(objects, object)
+		}
+		err = api.itemsUpdate(objects, len(objects))
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (api *API) itemsUpdate(objects interface{}, count int) (err error) {
+	response, err := api.CallWithError("item.update", objects)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	itemids := result["itemids"].([]interface{})
+	if count != len(itemids) {
+		err = &ExpectedMore{count, len(itemids)}
+	}
+	return
+}
+
+// Enables items with given ids.
+func (api *API) ItemsEnable(ids []string) (err error) {
+	return api.ItemsUpdateByIds(ids, Params{"status": ItemEnabled})
+}
+
+// Disables items with given ids.
+func (api *API) ItemsDisable(ids []string) (err error) {
+	return api.ItemsUpdateByIds(ids, Params{"status": ItemDisabled})
+}
+
+// Gets items which keys match pattern, for example "net.if.*[eth0*".
+// Pattern may contain "*" wildcards. If hostIds is empty, items of all hosts and templates are returned.
+func (api *API) ItemsGetByKeyPattern(hostIds []string, pattern string) (res Items, err error) {
+	params := Params{
+		"search":                 map[string]string{"key_": pattern},
+		"searchWildcardsEnabled": true,
+	}
+	if len(hostIds) > 0 {
+		params["hostids"] = hostIds
+	}
+	return api.ItemsGet(params)
+}
+
+// Enables items which keys match pattern, see ItemsGetByKeyPattern. Returns affected items.
+func (api *API) ItemsEnableByKey(hostIds []string, pattern string) (res Items, err error) {
+	return api.itemsSetStatusByKey(hostIds, pattern, ItemEnabled)
+}
+
+// Disables items which keys match pattern, see ItemsGetByKeyPattern. Returns affected items.
+func (api *API) ItemsDisableByKey(hostIds []string, pattern string) (res Items, err error) {
+	return api.itemsSetStatusByKey(hostIds, pattern, ItemDisabled)
+}
+
+func (api *API) itemsSetStatusByKey(hostIds []string, pattern string, status ItemStatus) (res Items, err error) {
+	res, err = api.ItemsGetByKeyPattern(hostIds, pattern)
+	if err != nil || len(res) == 0 {
+		return
+	}
+
+	err = api.ItemsUpdateByIds(res.Ids(), Params{"status": status})
+	if err == nil {
+		for i := range res {
+			res[i].Status = status
+		}
+	}
+	return
+}
+
 // Wrapper for item.delete: https://www.zabbix.com/documentation/2.2/manual/appendix/api/item/delete
 // Cleans ItemId in all items elements if call succeed.
 func (api *API) ItemsDelete(items Items) (err error) {
-	ids := make([]string, len(items))
-	for i, item := range items {
-		ids[i] = item.ItemId
-	}
-
-	err = api.ItemsDeleteByIds(ids)
+	err = api.ItemsDeleteByIds(items.Ids())
 	if err == nil {
 		for i := range items {
 			items[i].ItemId = ""
@@ -0,0 +1,278 @@
+// item keys
+
+package zabbix
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Parameter of item key. Array parameters like [a,b] have IsArray set and elements in Array.
+type ItemKeyParam struct {
+	Value   string
+	Quoted  bool // parameter was written in quotes, String() quotes only when needed
+	IsArray bool
+	Array   []ItemKeyParam
+}
+
+// Parsed item key like net.if.in[eth0,bytes].
+// https://www.zabbix.com/documentation/4.0/manual/config/items/item/key
+type ItemKey struct {
+	Name      string
+	Params    []ItemKeyParam
+	HasParams bool // key has brackets, so key[] differs from key
+}
+
+// Creates item key with plain parameters.
+func NewItemKey(name string, params ...string) *ItemKey {
+	k := &ItemKey{Name: name, HasParams: len(params) > 0}
+	for _, p := range params {
+		k.Params = append(k.Params, ItemKeyParam{Value: p})
+	}
+	return k
+}
+
+// Builds item key string from name and parameters, quoting them when needed.
+// Error is returned for parameters which can't be written, see Validate.
+func BuildItemKey(name string, params ...string) (string, error) {
+	k := NewItemKey(name, params...)
+	if err := k.Validate(); err != nil {
+		return "", err
+	}
+	return k.String(), nil
+}
+
+// Appends plain parameter and returns key for chaining.
+func (k *ItemKey) Add(value string) *ItemKey {
+	k.HasParams = true
+	k.Params = append(k.Params, ItemKeyParam{Value: value})
+	return k
+}
+
+// Appends array parameter and returns key for chaining.
+func (k *ItemKey) AddArray(values ...string) *ItemKey {
+	p := ItemKeyParam{IsArray: true}
+	for _, v := range values {
+		p.Array = append(p.Array, ItemKeyParam{Value: v})
+	}
+	k.HasParams = true
+	k.Params = append(k.Params, p)
+	return k
+}
+
+// Returns value of parameter i or empty string if there is no such parameter.
+func (k *ItemKey) Param(i int) string {
+	if i < len(k.Params) {
+		return k.Params[i].Value
+	}
+	return ""
+}
+
+// Returns key in canonical form: parameters are quoted only when needed and spaces around them are removed,
+// so net.if.in["eth0"] and net.if.in[ eth0 ] are both printed as net.if.in[eth0].
+func (k *ItemKey) String() string {
+	if !k.HasParams {
+		return k.Name
+	}
+	var b bytes.Buffer
+	b.WriteString(k.Name)
+	writeItemKeyParams(&b, k.Params)
+	return b.String()
+}
+
+func writeItemKeyParams(b *bytes.Buffer, params []ItemKeyParam) {
+	b.WriteByte('[')
+	for i, p := range params {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if p.IsArray {
+			writeItemKeyParams(b, p.Array)
+			continue
+		}
+		b.WriteString(quoteItemKeyParam(p.Value))
+	}
+	b.WriteByte(']')
+}
+
+// Returns true if parameter can't be written as is.
+func itemKeyParamNeedsQuotes(value string) bool {
+	return value != "" && (strings.ContainsAny(value, ",]") || strings.IndexAny(value[:1], `" [`) >= 0 || strings.HasSuffix(value, " "))
+}
+
+// Quotes parameter if it can't be written as is.
+func quoteItemKeyParam(value string) string {
+	if !itemKeyParamNeedsQuotes(value) {
+		return value
+	}
+	return `"` + strings.Replace(value, `"`, `\"`, -1) + `"`
+}
+
+// Checks that all parameters can be written. Zabbix doesn't allow quoted parameters to end with backslash,
+// so parameter which needs quotes, like `C:\dir, 2\`, can't be used.
+func (k *ItemKey) Validate() error {
+	return validateItemKeyParams(k.Name, k.Params)
+}
+
+func validateItemKeyParams(name string, params []ItemKeyParam) error {
+	for _, p := range params {
+		if p.IsArray {
+			if err := validateItemKeyParams(name, p.Array); err != nil {
+				return err
+			}
+			continue
+		}
+		if itemKeyParamNeedsQuotes(p.Value) && strings.HasSuffix(p.Value, `\`) {
+			return fmt.Errorf("Parameter %q of item key %s needs quotes and can't end with backslash", p.Value, name)
+		}
+	}
+	return nil
+}
+
+// Error returned by ParseItemKey. Pos is byte offset in Key.
+type ItemKeyError struct {
+	Key     string
+	Pos     int
+	Message string
+}
+
+func (e *ItemKeyError) Error() string {
+	return fmt.Sprintf("%s at position %d in item key %q", e.Message, e.Pos, e.Key)
+}
+
+// Parses item key. Array parameters may not be nested.
+func ParseItemKey(key string) (res *ItemKey, err error) {
+	p := &itemKeyParser{s: key}
+	for p.pos < len(key) && exprKeyNameChar(key[p.pos]) {
+		p.pos++
+	}
+	if p.pos == 0 {
+		return nil, p.errorf("expected item key name")
+	}
+	res = &ItemKey{Name: key[:p.pos]}
+	if p.pos == len(key) {
+		return
+	}
+	if key[p.pos] != '[' {
+		return nil, p.errorf("unexpected %q", key[p.pos:p.pos+1])
+	}
+
+	res.HasParams = true
+	res.Params, err = p.parseParams(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(key) {
+		return nil, p.errorf("unexpected %q after parameters", key[p.pos:p.pos+1])
+	}
+	return
+}
+
+type itemKeyParser struct {
+	s   string
+	pos int
+}
+
+func (p *itemKeyParser) errorf(format string, args ...interface{}) error {
+	return &ItemKeyError{Key: p.s, Pos: p.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// Parses parameters from "[" to matching "]".
+func (p *itemKeyParser) parseParams(nested bool) (params []ItemKeyParam, err error) {
+	p.pos++ // skip "["
+	for {
+		for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+			p.pos++
+		}
+		if p.pos >= len(p.s) {
+			return nil, p.errorf("unterminated parameters")
+		}
+
+		var param ItemKeyParam
+		switch p.s[p.pos] {
+		case '[':
+			if nested {
+				return nil, p.errorf("nested arrays are not allowed")
+			}
+			param.IsArray = true
+			param.Array, err = p.parseParams(true)
+			if err != nil {
+				return
+			}
+			for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+				p.pos++
+			}
+		case '"':
+			param.Quoted = true
+			param.Value, err = p.parseQuoted()
+			if err != nil {
+				return
+			}
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && p.s[p.pos] != ',' && p.s[p.pos] != ']' {
+				p.pos++
+			}
+			param.Value = strings.TrimRight(p.s[start:p.pos], " ")
+		}
+		params = append(params, param)
+
+		if p.pos >= len(p.s) {
+			return nil, p.errorf("unterminated parameters")
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return
+		default:
+			return nil, p.errorf("expected , or ]")
+		}
+	}
+}
+
+// Parses quoted parameter and following spaces.
+func (p *itemKeyParser) parseQuoted() (string, error) {
+	var b bytes.Buffer
+	for i := p.pos + 1; i < len(p.s); i++ {
+		switch c := p.s[i]; {
+		case c == '\\' && i+1 < len(p.s) && p.s[i+1] == '"':
+			b.WriteByte('"')
+			i++
+		case c == '"':
+			p.pos = i + 1
+			for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+				p.pos++
+			}
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated quoted parameter")
+}
+
+// Returns item key in canonical form, see ItemKey.String(). Keys which can't be parsed are returned as is.
+func NormalizeItemKey(key string) string {
+	k, err := ParseItemKey(key)
+	if err != nil {
+		return key
+	}
+	return k.String()
+}
+
+// Converts slice to map by normalized key, see NormalizeItemKey.
+// Unlike ByKey returns error if there are items with the same normalized key.
+func (items Items) ByNormalizedKey() (res map[string]Item, err error) {
+	res = make(map[string]Item, len(items))
+	for _, i := range items {
+		key := NormalizeItemKey(i.Key)
+		if prev, present := res[key]; present {
+			return nil, fmt.Errorf("Duplicate key %s (%s and %s)", key, prev.Key, i.Key)
+		}
+		res[key] = i
+	}
+	return
+}
@@ -0,0 +1,88 @@
+package zabbix_test
+
+import (
+	. "."
+	"testing"
+)
+
+func TestItemKeyParse(t *testing.T) {
+	for key, canonical := range map[string]string{
+		`agent.ping`:                           `agent.ping`,
+		`net.if.in[eth0]`:                      `net.if.in[eth0]`,
+		`net.if.in["eth0"]`:                    `net.if.in[eth0]`,
+		`net.if.in[ "eth0" , bytes ]`:          `net.if.in[eth0,bytes]`,
+		`key[]`:                                `key[]`,
+		`key[,,]`:                              `key[,,]`,
+		`proc.num[,,run]`:                      `proc.num[,,run]`,
+		`log[/var/log/app.log,"a,b]"]`:         `log[/var/log/app.log,"a,b]"]`,
+		`key["say \"hi\""]`:                    `key[say "hi"]`,
+		`key[a"b]`:                             `key[a"b]`,
+		`key[[a, "b"],c]`:                      `key[[a,b],c]`,
+		`key["[a]"]`:                           `key["[a]"]`,
+		`key[" a"]`:                            `key[" a"]`,
+		`vfs.fs.size[{#FSNAME},pfree]`:         `vfs.fs.size[{#FSNAME},pfree]`,
+		`web.page.get[localhost,/path?a=b,80]`: `web.page.get[localhost,/path?a=b,80]`,
+	} {
+		k, err := ParseItemKey(key)
+		if err != nil {
+			t.Errorf("%s: %s", key, err)
+			continue
+		}
+		if k.String() != canonical {
+			t.Errorf("%s: expected %s, got %s", key, canonical, k.String())
+		}
+	}
+
+	k, err := ParseItemKey(`key[[a, "b,c"],"d"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Name != "key" || len(k.Params) != 2 || !k.Params[0].IsArray || k.Params[0].Array[1].Value != "b,c" || !k.Params[1].Quoted || k.Param(1) != "d" {
+		t.Errorf("Bad key: %#v", k)
+	}
+
+	for _, key := range []string{``, `[a]`, `key[a`, `key["a]`, `key[[a,[b]]]`, `key[a]b`, `key a`, `key["a"b]`} {
+		_, err := ParseItemKey(key)
+		if _, ok := err.(*ItemKeyError); !ok {
+			t.Errorf("%s: expected error, got %v", key, err)
+		}
+	}
+}
+
+func TestItemKeyBuild(t *testing.T) {
+	if k, err := BuildItemKey("vfs.fs.size", "/", "pfree"); err != nil || k != "vfs.fs.size[/,pfree]" {
+		t.Errorf("Bad key: %s (%v)", k, err)
+	}
+
+	// backslash at the end is allowed only in unquoted parameters
+	k, err := BuildItemKey("vfs.file.size", `C:\dir\`, `C:\a b\c`)
+	if err != nil || k != `vfs.file.size[C:\dir\,C:\a b\c]` {
+		t.Errorf("Bad key: %s (%v)", k, err)
+	}
+	if parsed, err := ParseItemKey(k); err != nil || parsed.Param(0) != `C:\dir\` || parsed.String() != k {
+		t.Errorf("Bad round trip of %s: %#v (%v)", k, parsed, err)
+	}
+	if k, err = BuildItemKey("vfs.file.size", `C:\dir, 2\`); err == nil {
+		t.Errorf("Expected error for quoted parameter with backslash at the end, got %s", k)
+	}
+	if err = NewItemKey("key").AddArray("a", ` b\`).Validate(); err == nil {
+		t.Error("Expected error for array parameter with backslash at the end")
+	}
+	if k := NewItemKey("key").Add("a,b").AddArray("c", "d]").Add(`"e"`).String(); k != `key["a,b",[c,"d]"],"\"e\""]` {
+		t.Errorf("Bad key: %s", k)
+	}
+
+	items := Items{{Key: `net.if.in["eth0"]`}, {Key: `net.if.in[eth1]`}}
+	byKey, err := items.ByNormalizedKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := byKey["net.if.in[eth0]"]; !ok {
+		t.Errorf("Bad map: %#v", byKey)
+	}
+	items = append(items, Item{Key: `net.if.in[ eth0 ]`})
+	_, err = items.ByNormalizedKey()
+	if err == nil {
+		t.Errorf("Expected duplicate key error")
+	}
+}
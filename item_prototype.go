@@ -0,0 +1,120 @@
+// item prototypes
+
+package zabbix
+
+import (
+	"github.com/wOvAN/reflector"
+)
+
+// https://www.zabbix.com/documentation/5.0/manual/api/reference/itemprototype/object
+type ItemPrototype struct {
+	ItemId       string     `json:"itemid,omitempty"`
+	Delay        string     `json:"delay"`
+	HostId       string     `json:"hostid"`
+	InterfaceId  string     `json:"interfaceid,omitempty"`
+	Key          string     `json:"key_"`
+	Name         string     `json:"name"`
+	Type         ItemType   `json:"type"`
+	ValueType    ValueType  `json:"value_type"`
+	Description  string     `json:"description,omitempty"`
+	History      string     `json:"history,omitempty"`
+	Trends       string     `json:"trends,omitempty"`
+	Units        string     `json:"units,omitempty"`
+	Status       ItemStatus `json:"status"`
+	TemplateId   string     `json:"templateid,omitempty"`
+	MasterItemId string     `json:"master_itemid,omitempty"`
+
+	// Returned by itemprototype.get only with SelectPreprocessing
+	Preprocessing PreprocessingSteps `json:"preprocessing,omitempty"`
+
+	// Fields below used only when creating item prototypes
+	RuleId         string   `json:"ruleid,omitempty"`
+	ApplicationIds []string `json:"applications,omitempty"`
+}
+
+type ItemPrototypes []ItemPrototype
+
+// Returns ids of all item prototypes.
+func (prototypes ItemPrototypes) Ids() (res []string) {
+	res = make([]string, len(prototypes))
+	for i, prototype := range prototypes {
+		res[i] = prototype.ItemId
+	}
+	return
+}
+
+// Wrapper for itemprototype.get: https://www.zabbix.com/documentation/5.0/manual/api/reference/itemprototype/get
+func (api *API) ItemPrototypesGet(params Params) (res ItemPrototypes, err error) {
+	if _, present := params["output"]; !present {
+		params["output"] = "extend"
+	}
+	response, err := api.CallWithError("itemprototype.get", params)
+	if err != nil {
+		return
+	}
+
+	reflector.MapsToStructs2(response.Result.([]interface{}), &res, reflector.Strconv, "json")
+	return
+}
+
+// Gets item prototypes of discovery rule with preprocessing steps.
+func (api *API) ItemPrototypesGetByRuleId(ruleId string) (res ItemPrototypes, err error) {
+	return api.ItemPrototypesGet(Params{"discoveryids": ruleId, SelectPreprocessing: "extend"})
+}
+
+// Wrapper for itemprototype.create: https://www.zabbix.com/documentation/5.0/manual/api/reference/itemprototype/create
+func (api *API) ItemPrototypesCreate(prototypes ItemPrototypes) (err error) {
+	response, err := api.CallWithError("itemprototype.create", prototypes)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	itemids := result["itemids"].([]interface{})
+	for i, id := range itemids {
+		prototypes[i].ItemId = id.(string)
+	}
+	return
+}
+
+// Wrapper for itemprototype.update: https://www.zabbix.com/documentation/5.0/manual/api/reference/itemprototype/update
+func (api *API) ItemPrototypesUpdate(prototypes ItemPrototypes) (err error) {
+	response, err := api.CallWithError("itemprototype.update", prototypes)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	itemids := result["itemids"].([]interface{})
+	if len(prototypes) != len(itemids) {
+		err = &ExpectedMore{len(prototypes), len(itemids)}
+	}
+	return
+}
+
+// Wrapper for itemprototype.delete: https://www.zabbix.com/documentation/5.0/manual/api/reference/itemprototype/delete
+// Cleans ItemId in all prototypes elements if call succeed.
+func (api *API) ItemPrototypesDelete(prototypes ItemPrototypes) (err error) {
+	err = api.ItemPrototypesDeleteByIds(prototypes.Ids())
+	if err == nil {
+		for i := range prototypes {
+			prototypes[i].ItemId = ""
+		}
+	}
+	return
+}
+
+// Wrapper for itemprototype.delete: https://www.zabbix.com/documentation/5.0/manual/api/reference/itemprototype/delete
+func (api *API) ItemPrototypesDeleteByIds(ids []string) (err error) {
+	response, err := api.CallWithError("itemprototype.delete", ids)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	prototypeids := result["prototypeids"].([]interface{})
+	if len(ids) != len(prototypeids) {
+		err = &ExpectedMore{len(ids), len(prototypeids)}
+	}
+	return
+}
@@ -2,6 +2,10 @@ package zabbix_test
 
 import (
 	. "."
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -48,5 +52,234 @@ func TestItems(t *testing.T) {
 	}
 
 	item := CreateItem(app, t)
-	DeleteItem(item, t)
+	defer DeleteItem(item, t)
+
+	item.Delay = "42"
+	err = api.ItemsUpdate(Items{*item})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.ItemsDisable([]string{item.ItemId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err = api.ItemsGetByApplicationId(app.ApplicationId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Delay != "42" || items[0].Status != ItemDisabled {
+		t.Errorf("Failed to update item: %#v", items)
+	}
+
+	items, err = api.ItemsEnableByKey([]string{host.HostId}, "key.lala.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Status != ItemEnabled {
+		t.Errorf("Failed to enable item: %#v", items)
+	}
+}
+
+func TestItemsPreprocessing(t *testing.T) {
+	api := getAPI(t)
+
+	group := CreateHostGroup(t)
+	defer DeleteHostGroup(group, t)
+
+	host := CreateHost(group, t)
+	defer DeleteHost(host, t)
+
+	app := CreateApplication(host, t)
+	defer DeleteApplication(app, t)
+
+	item := CreateItem(app, t)
+	defer DeleteItem(item, t)
+
+	item.Preprocessing = PreprocessingSteps{
+		MultiplierStep("8"),
+		ChangePerSecondStep().OnErrorSetValue("0"),
+	}
+	err := api.ItemsUpdate(Items{*item})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := api.ItemsGetWithPreprocessing([]string{item.ItemId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || !reflect.DeepEqual(items[0].Preprocessing, item.Preprocessing) {
+		t.Errorf("Preprocessing steps are not equal:\n%#v\n%#v", item.Preprocessing, items)
+	}
+}
+
+func TestDependentItems(t *testing.T) {
+	api := getAPI(t)
+
+	group := CreateHostGroup(t)
+	defer DeleteHostGroup(group, t)
+
+	host := CreateHost(group, t)
+	defer DeleteHost(host, t)
+
+	httpItem := NewHTTPAgentItem(host.HostId, "master.lala", "master", "http://localhost/status", "1m")
+	httpItem.AddQueryField("format", "json")
+	httpItem.SetHeader("Accept", "application/json")
+	master := &httpItem
+	dependents := Items{
+		NewJSONPathItem("master.lala.a", "a", Unsigned, "$.a"),
+		NewJSONPathItem("master.lala.b", "b", Float, "$.b"),
+	}
+	err := api.ItemsCreateWithDependents(master, dependents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DeleteItem(master, t)
+
+	items, err := api.ItemsGet(Params{"hostids": host.HostId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items.Dependents(master.ItemId)) != 2 {
+		t.Errorf("Bad dependent items: %#v", items)
+	}
+
+	// dependent key is already used on host, so master must be removed
+	broken := &Item{HostId: host.HostId, Key: "master.broken", Name: "broken", Type: ZabbixTrapper, ValueType: Text}
+	err = api.ItemsCreateWithDependents(broken, Items{NewJSONPathItem("master.lala.a", "a", Unsigned, "$.a")})
+	if err == nil {
+		t.Fatal("Expected error for duplicate key")
+	}
+	if broken.ItemId != "" {
+		t.Errorf("Master item was not rolled back: %#v", broken)
+	}
+}
+
+// Returns API creating items with sequential ids, item.create calls fail after failAfter calls if it is positive.
+// Keys and master item ids of created batches and ids of deleted items are appended to calls.
+func stubItemsAPI(failAfter int, calls *[]string) *API {
+	lastId, creates := 100, 0
+	return stubAPI(func(method string, params interface{}) interface{} {
+		switch method {
+		case "item.create":
+			creates++
+			if failAfter > 0 && creates > failAfter {
+				return &Error{Code: -32602, Message: "Invalid params.", Data: "Item already exists."}
+			}
+			var call []string
+			var ids []interface{}
+			for _, item := range params.([]interface{}) {
+				item := item.(map[string]interface{})
+				lastId++
+				ids = append(ids, fmt.Sprint(lastId))
+				call = append(call, fmt.Sprintf("%s<%v", item["key_"], item["master_itemid"]))
+			}
+			*calls = append(*calls, "create "+strings.Join(call, " "))
+			return map[string]interface{}{"itemids": ids}
+		case "item.delete":
+			*calls = append(*calls, fmt.Sprint("delete ", params))
+			return map[string]interface{}{"itemids": params}
+		}
+		return &Error{Code: -32602, Message: "Invalid params.", Data: "Unknown method " + method}
+	})
+}
+
+func TestItemsCreateOrdered(t *testing.T) {
+	var calls []string
+	items := Items{
+		{HostId: "1", Key: "c", Type: ZabbixTrapper},
+		{HostId: "1", Key: "b", Type: ZabbixTrapper},
+		{HostId: "1", Key: "a", Type: ZabbixTrapper},
+		{HostId: "1", Key: "d", Type: ZabbixTrapper},
+	}
+	masterKeys := map[string]string{"c": "b", "b": "a"}
+	if err := stubItemsAPI(0, &calls).ItemsCreateOrdered(items, masterKeys); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"create a<<nil> d<<nil>", "create b<101", "create c<103"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected calls %q, got %q", expected, calls)
+	}
+	if items[0].ItemId != "104" || items[0].MasterItemId != "103" || items[0].Type != DependentItem || items[3].ItemId != "102" {
+		t.Errorf("Bad items %#v", items)
+	}
+
+	calls = nil
+	err := stubItemsAPI(0, &calls).ItemsCreateOrdered(Items{{HostId: "1", Key: "b"}}, map[string]string{"b": "a"})
+	if err == nil || err.Error() != "Master item a for b not found" || calls != nil {
+		t.Errorf("Expected error for missing master, got %v and calls %q", err, calls)
+	}
+
+	err = stubItemsAPI(0, &calls).ItemsCreateOrdered(Items{{HostId: "1", Key: "a"}, {HostId: "2", Key: "b"}}, nil)
+	if err == nil || calls != nil {
+		t.Errorf("Expected error for items of different hosts, got %v and calls %q", err, calls)
+	}
+
+	items = Items{{HostId: "1", Key: "a"}, {HostId: "1", Key: "b"}, {HostId: "1", Key: "c"}, {HostId: "1", Key: "d"}, {HostId: "1", Key: "e"}}
+	err = stubItemsAPI(0, &calls).ItemsCreateOrdered(items, map[string]string{"e": "d", "d": "c", "c": "b", "b": "a"})
+	if err == nil || err.Error() != "Dependency chain of item e is deeper than 3 levels" || calls != nil {
+		t.Errorf("Expected error for deep chain, got %v and calls %q", err, calls)
+	}
+
+	items = Items{{HostId: "1", Key: "a", Type: ZabbixTrapper}, {HostId: "1", Key: "b", Type: ZabbixTrapper}}
+	err = stubItemsAPI(1, &calls).ItemsCreateOrdered(items, map[string]string{"b": "a"})
+	expected = []string{"create a<<nil>", "delete [101]"}
+	if err == nil || !reflect.DeepEqual(calls, expected) || items[0].ItemId != "" || items[1].ItemId != "" {
+		t.Errorf("Expected rollback, got %v, calls %q and items %#v", err, calls, items)
+	}
+}
+
+func TestItemsUpdateBatches(t *testing.T) {
+	defer func(size int) { ItemsUpdateBatchSize = size }(ItemsUpdateBatchSize)
+	ItemsUpdateBatchSize = 2
+
+	var batches []string
+	api := stubAPI(func(method string, params interface{}) interface{} {
+		if method != "item.update" {
+			t.Errorf("Unexpected call %s", method)
+		}
+		var ids []interface{}
+		for _, item := range params.([]interface{}) {
+			ids = append(ids, item.(map[string]interface{})["itemid"])
+		}
+		data, _ := json.Marshal(params)
+		batches = append(batches, string(data))
+		return map[string]interface{}{"itemids": ids}
+	})
+
+	var items Items
+	for i := 1; i <= 5; i++ {
+		items = append(items, Item{ItemId: fmt.Sprint(i), HostId: "10", Key: fmt.Sprint("key", i), Name: "Key",
+			Type: ZabbixTrapper, ValueType: Unsigned, Status: ItemDisabled, Error: "Not supported", TemplateId: "0"})
+	}
+	if err := api.ItemsUpdate(items); err != nil {
+		t.Fatal(err)
+	}
+	item := `{"description":"","itemid":"%d","key_":"key%d","name":"Key","status":1,"type":2,"value_type":3}`
+	expected := []string{
+		"[" + fmt.Sprintf(item, 1, 1) + "," + fmt.Sprintf(item, 2, 2) + "]",
+		"[" + fmt.Sprintf(item, 3, 3) + "," + fmt.Sprintf(item, 4, 4) + "]",
+		"[" + fmt.Sprintf(item, 5, 5) + "]",
+	}
+	if !reflect.DeepEqual(batches, expected) {
+		t.Errorf("Expected batches\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(batches, "\n"))
+	}
+	if items[0].HostId != "10" || items[0].TemplateId != "0" {
+		t.Errorf("Items are modified: %#v", items[0])
+	}
+
+	batches = nil
+	ItemsUpdateBatchSize = 0
+	if err := api.ItemsUpdateByIds([]string{"1", "2", "3"}, Params{"delay": "5m"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 1 || batches[0] != `[{"delay":"5m","itemid":"1"},{"delay":"5m","itemid":"2"},{"delay":"5m","itemid":"3"}]` {
+		t.Errorf("Expected single batch, got %v", batches)
+	}
+
+	batches = nil
+	if err := api.ItemsUpdate(Items{{ItemId: "1", Delay: "5m"}}); err == nil || len(batches) != 0 {
+		t.Errorf("Expected error for partial item, got %v", err)
+	}
 }
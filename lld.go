@@ -0,0 +1,121 @@
+// low-level discovery data
+
+package zabbix
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Entity discovered by LLD rule: macro like {#IFNAME} to value.
+type LLDRow map[string]string
+
+// Sets macro value formatted with fmt.Sprint. Macro may be given without braces and #, so "IFNAME" is {#IFNAME}.
+func (r LLDRow) Set(macro string, value interface{}) LLDRow {
+	r[LLDMacro(macro)] = fmt.Sprint(value)
+	return r
+}
+
+// Payload of discovery rule.
+// https://www.zabbix.com/documentation/4.0/manual/discovery/low_level_discovery#creating_custom_lld_rules
+type LLDData []LLDRow
+
+// Format of LLD JSON.
+type LLDFormat int
+
+const (
+	LLDLegacy LLDFormat = 0 // {"data":[...]}, accepted by all versions
+	LLDArray  LLDFormat = 1 // plain array, Zabbix 4.2+
+)
+
+var lldMacroRE = regexp.MustCompile(`^\{#[A-Z0-9_.]+\}$`)
+
+// Returns macro in {#NAME} form, name may already have it.
+func LLDMacro(name string) string {
+	if len(name) > 0 && name[0] == '{' {
+		return name
+	}
+	return "{#" + name + "}"
+}
+
+// Checks that macro is like {#NAME}, where name consists of A-Z, 0-9, _ and . only.
+func ValidateLLDMacro(macro string) error {
+	if !lldMacroRE.MatchString(macro) {
+		return fmt.Errorf("Invalid LLD macro %q", macro)
+	}
+	return nil
+}
+
+// Appends row after validating its macros.
+func (d *LLDData) Add(row LLDRow) error {
+	if err := row.validate(); err != nil {
+		return err
+	}
+	*d = append(*d, row)
+	return nil
+}
+
+func (r LLDRow) validate() error {
+	macros := make([]string, 0, len(r))
+	for macro := range r {
+		macros = append(macros, macro)
+	}
+	sort.Strings(macros)
+	for _, macro := range macros {
+		if err := ValidateLLDMacro(macro); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validates macros of all rows.
+func (d LLDData) Validate() error {
+	for i, row := range d {
+		if err := row.validate(); err != nil {
+			return fmt.Errorf("Row %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// Returns validated JSON in given format.
+func (d LLDData) Marshal(format LLDFormat) ([]byte, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	rows := d
+	if rows == nil {
+		rows = LLDData{}
+	}
+	if format == LLDArray {
+		return json.Marshal(rows)
+	}
+	return json.Marshal(map[string]LLDData{"data": rows})
+}
+
+// Parses LLD JSON in any format.
+func ParseLLDData(b []byte) (res LLDData, err error) {
+	if err = json.Unmarshal(b, &res); err == nil {
+		return
+	}
+	var legacy struct {
+		Data LLDData `json:"data"`
+	}
+	if err = json.Unmarshal(b, &legacy); err != nil {
+		return nil, err
+	}
+	return legacy.Data, nil
+}
+
+// Sends discovery data to trapper discovery rule key of host.
+func (s *Sender) SendDiscovery(host, key string, data LLDData, format LLDFormat) (*SenderResponse, error) {
+	b, err := data.Marshal(format)
+	if err != nil {
+		return nil, err
+	}
+	return s.Send([]SenderValue{NewSenderValue(host, key, string(b), time.Now())})
+}
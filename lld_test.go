@@ -0,0 +1,52 @@
+package zabbix_test
+
+import (
+	. "."
+	"testing"
+)
+
+func TestLLDData(t *testing.T) {
+	var data LLDData
+	if err := data.Add(LLDRow{}.Set("IFNAME", "eth0").Set("{#MTU}", 1500)); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.Add(LLDRow{"{#ifname}": "lo"}); err == nil {
+		t.Error("Expected error for lowercase macro")
+	}
+	if err := data.Add(LLDRow{"{$IFNAME}": "lo"}); err == nil {
+		t.Error("Expected error for user macro")
+	}
+
+	b, err := data.Marshal(LLDLegacy)
+	if err != nil || string(b) != `{"data":[{"{#IFNAME}":"eth0","{#MTU}":"1500"}]}` {
+		t.Errorf("Bad legacy JSON %s (%v)", b, err)
+	}
+	b, err = data.Marshal(LLDArray)
+	if err != nil || string(b) != `[{"{#IFNAME}":"eth0","{#MTU}":"1500"}]` {
+		t.Errorf("Bad array JSON %s (%v)", b, err)
+	}
+	if b, _ = LLDData(nil).Marshal(LLDArray); string(b) != `[]` {
+		t.Errorf("Bad empty JSON %s", b)
+	}
+
+	for _, s := range []string{`[{"{#A}":"1"}]`, `{"data":[{"{#A}":"1"}]}`} {
+		parsed, err := ParseLLDData([]byte(s))
+		if err != nil || len(parsed) != 1 || parsed[0]["{#A}"] != "1" {
+			t.Errorf("%s: bad data %v (%v)", s, parsed, err)
+		}
+	}
+
+	var received map[string]interface{}
+	l := serveTrapper(t, func(request map[string]interface{}) interface{} {
+		received = request["data"].([]interface{})[0].(map[string]interface{})
+		return Params{"response": "success", "info": "processed: 1; failed: 0; total: 1; seconds spent: 0.1"}
+	})
+	defer l.Close()
+	res, err := NewSender(l.Addr().String()).SendDiscovery("h", "net.if.discovery", data, LLDArray)
+	if err != nil || res.Processed != 1 {
+		t.Fatalf("Bad response %#v (%v)", res, err)
+	}
+	if received["key"] != "net.if.discovery" || received["value"] != `[{"{#IFNAME}":"eth0","{#MTU}":"1500"}]` {
+		t.Errorf("Bad value %v", received)
+	}
+}
@@ -0,0 +1,240 @@
+// migration of hosts between servers
+
+package zabbix
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Result of migration.
+type MigrationReport struct {
+	// Old to new ids by kind, see IdMap. Items and triggers include ones inherited from templates.
+	// Objects which already existed on destination are mapped too.
+	Ids IdMap
+
+	// Items and triggers of source hosts not found on destination, like "item web:agent.ping",
+	// and dropped trigger dependencies, see DroppedDependenciesError.
+	Missing []string
+}
+
+// Returns report with one "kind old -> new" line for each host, item and trigger, sorted by kind and old id,
+// followed by missing objects.
+func (r *MigrationReport) String() string {
+	var b bytes.Buffer
+	for _, kind := range []string{"host", "item", "trigger"} {
+		ids := r.Ids[kind]
+		old := make([]string, 0, len(ids))
+		for id := range ids {
+			old = append(old, id)
+		}
+		sort.Sort(byNumericId(old))
+		for _, id := range old {
+			fmt.Fprintf(&b, "%s %s -> %s\n", kind, id, ids[id])
+		}
+	}
+	for _, m := range r.Missing {
+		fmt.Fprintf(&b, "missing %s\n", m)
+	}
+	return b.String()
+}
+
+// Sorts ids numerically, shorter ids go first.
+type byNumericId []string
+
+func (ids byNumericId) Len() int      { return len(ids) }
+func (ids byNumericId) Swap(i, j int) { ids[i], ids[j] = ids[j], ids[i] }
+func (ids byNumericId) Less(i, j int) bool {
+	if len(ids[i]) != len(ids[j]) {
+		return len(ids[i]) < len(ids[j])
+	}
+	return ids[i] < ids[j]
+}
+
+// Gets hosts of host groups with given names together with their templates (including inherited ones),
+// proxies and all host groups they are in. Scripts are not included.
+// Duplicate names are ignored, error lists names of host groups which are not found.
+func (api *API) HostGroupsBackup(names []string) (b *Backup, err error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("No host groups to back up")
+	}
+	groups, err := api.HostGroupsGet(Params{"filter": map[string]interface{}{"name": names}})
+	if err != nil {
+		return
+	}
+	groupIds := make([]string, len(groups))
+	found := make(map[string]bool, len(groups))
+	for i, g := range groups {
+		groupIds[i] = g.GroupId
+		found[g.Name] = true
+	}
+	var missing []string
+	for _, name := range names {
+		if !found[name] {
+			found[name] = true
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Host groups are not found: %s", strings.Join(missing, ", "))
+	}
+
+	if b, err = api.newBackup(); err != nil {
+		return
+	}
+	if b.Hosts, err = api.HostsGet(backupHostParams(Params{"groupids": groupIds})); err != nil {
+		return nil, err
+	}
+
+	var templateIds, proxyIds []string
+	for _, h := range b.Hosts {
+		for _, g := range h.GroupIds {
+			if !containsId(groupIds, g.GroupId) {
+				groupIds = append(groupIds, g.GroupId)
+			}
+		}
+		for _, t := range h.ParentTemplates {
+			if !containsId(templateIds, t.TemplateId) {
+				templateIds = append(templateIds, t.TemplateId)
+			}
+		}
+		if h.ProxyHostID != "" && h.ProxyHostID != "0" && !containsId(proxyIds, h.ProxyHostID) {
+			proxyIds = append(proxyIds, h.ProxyHostID)
+		}
+	}
+
+	// templates are got level by level, like in TemplateGraphForHosts
+	seen := make(map[string]bool)
+	for len(templateIds) > 0 {
+		var templates Templates
+		if templates, err = api.TemplatesGet(backupTemplateParams(Params{"templateids": templateIds})); err != nil {
+			return nil, err
+		}
+		for _, t := range templates {
+			seen[t.TemplateId] = true
+		}
+		templateIds = nil
+		for _, t := range templates {
+			for _, g := range t.Groups {
+				if !containsId(groupIds, g.GroupId) {
+					groupIds = append(groupIds, g.GroupId)
+				}
+			}
+			for _, p := range t.ParentTemplates {
+				if !seen[p.TemplateId] && !containsId(templateIds, p.TemplateId) {
+					templateIds = append(templateIds, p.TemplateId)
+				}
+			}
+		}
+		b.Templates = append(b.Templates, templates...)
+	}
+
+	if b.HostGroups, err = api.HostGroupsGet(Params{"groupids": groupIds}); err != nil {
+		return nil, err
+	}
+	if len(proxyIds) > 0 {
+		if b.Proxies, err = api.ProxyGet(Params{"proxyids": proxyIds, SelectInterface: "extend"}); err != nil {
+			return nil, err
+		}
+	}
+	if err = api.backupChildren(b); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Copies hosts of host groups with given names from src to dst, see HostGroupsBackup and ConfigurationRestore.
+// Host groups, proxies and templates are resolved by name, missing ones are created before hosts.
+// Hosts which already exist on dst are not changed, but their items and triggers are mapped.
+// On error report contains ids of objects created so far.
+func MigrateHostGroups(src, dst *API, names []string) (report *MigrationReport, err error) {
+	b, err := src.HostGroupsBackup(names)
+	if err != nil {
+		return
+	}
+	report = &MigrationReport{}
+	report.Ids, err = dst.ConfigurationRestore(b)
+	if e, ok := err.(*DroppedDependenciesError); ok {
+		for _, d := range e.Dropped {
+			report.Missing = append(report.Missing, "dependency "+d)
+		}
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+	err = report.mapChildren(src, dst, b)
+	return
+}
+
+// Maps all items and triggers of migrated hosts, including inherited ones, by key and expression.
+func (r *MigrationReport) mapChildren(src, dst *API, b *Backup) (err error) {
+	var srcIds, dstIds []string
+	hostNames := make(map[string]string)
+	for _, h := range b.Hosts {
+		if id, ok := r.Ids.Get("host", h.HostId); ok {
+			srcIds = append(srcIds, h.HostId)
+			dstIds = append(dstIds, id)
+			hostNames[h.HostId] = h.Host
+		}
+	}
+	if len(srcIds) == 0 {
+		return
+	}
+
+	itemParams := func(ids []string) Params {
+		return Params{"hostids": ids, "output": []string{"itemid", "hostid", "key_"}, "filter": map[string]interface{}{"flags": 0}}
+	}
+	srcItems, err := src.ItemsGet(itemParams(srcIds))
+	if err != nil {
+		return
+	}
+	dstItems, err := dst.ItemsGet(itemParams(dstIds))
+	if err != nil {
+		return
+	}
+	items := make(map[string]string, len(dstItems))
+	for _, i := range dstItems {
+		items[i.HostId+"\x00"+NormalizeItemKey(i.Key)] = i.ItemId
+	}
+	for _, i := range srcItems {
+		hostId, _ := r.Ids.Get("host", i.HostId)
+		if id, ok := items[hostId+"\x00"+NormalizeItemKey(i.Key)]; ok {
+			r.Ids.Set("item", i.ItemId, id)
+		} else {
+			r.Missing = append(r.Missing, fmt.Sprintf("item %s:%s", hostNames[i.HostId], i.Key))
+		}
+	}
+
+	// expanded expressions refer to hosts by name, which is the same on both servers
+	triggerParams := func(ids []string) Params {
+		return Params{
+			"hostids":        ids,
+			"output":         []string{"triggerid", "description", "expression"},
+			"filter":         map[string]interface{}{"flags": 0},
+			expandExpression: true,
+		}
+	}
+	srcTriggers, err := src.TriggersGet(triggerParams(srcIds))
+	if err != nil {
+		return
+	}
+	dstTriggers, err := dst.TriggersGet(triggerParams(dstIds))
+	if err != nil {
+		return
+	}
+	triggers := make(map[string]string, len(dstTriggers))
+	for _, t := range dstTriggers {
+		triggers[t.Description+"\x00"+normalizeExpression(t.Expression)] = t.TriggerId
+	}
+	for _, t := range srcTriggers {
+		if id, ok := triggers[t.Description+"\x00"+normalizeExpression(t.Expression)]; ok {
+			r.Ids.Set("trigger", t.TriggerId, id)
+		} else {
+			r.Missing = append(r.Missing, fmt.Sprintf("trigger %s: %s", t.Description, t.Expression))
+		}
+	}
+	return
+}
@@ -0,0 +1,177 @@
+package zabbix_test
+
+import (
+	. "."
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrationReport(t *testing.T) {
+	ids := IdMap{}
+	ids.Set("host", "10105", "10321")
+	ids.Set("host", "9999", "10320")
+	ids.Set("item", "23000", "30000")
+	ids.Set("hostgroup", "2", "2")
+	r := &MigrationReport{Ids: ids, Missing: []string{"item web:agent.ping"}}
+
+	expected := "host 9999 -> 10320\nhost 10105 -> 10321\nitem 23000 -> 30000\nmissing item web:agent.ping\n"
+	if s := r.String(); s != expected {
+		t.Errorf("Bad report:\n%s", s)
+	}
+}
+
+func TestHostGroupsBackupMissing(t *testing.T) {
+	api := stubAPI(func(method string, params interface{}) interface{} {
+		if method != "hostgroup.get" {
+			t.Errorf("Unexpected call %s", method)
+		}
+		return []interface{}{map[string]interface{}{"groupid": "2", "name": "Servers"}}
+	})
+	_, err := api.HostGroupsBackup([]string{"Servers", "Databases", "Servers", "Web", "Databases"})
+	if err == nil || err.Error() != "Host groups are not found: Databases, Web" {
+		t.Errorf("Expected error for missing groups, got %v", err)
+	}
+}
+
+func TestMigrateHostGroupsCreates(t *testing.T) {
+	src := stubAPI(func(method string, params interface{}) interface{} {
+		p, _ := params.(map[string]interface{})
+		switch method {
+		case "APIInfo.version":
+			return "4.0.0"
+		case "hostgroup.get":
+			if p["groupids"] == nil {
+				return []interface{}{map[string]interface{}{"groupid": "2", "name": "Servers"}}
+			}
+			return []interface{}{
+				map[string]interface{}{"groupid": "2", "name": "Servers"},
+				map[string]interface{}{"groupid": "3", "name": "Templates"},
+			}
+		case "host.get":
+			return []interface{}{map[string]interface{}{"hostid": "10", "host": "web", "name": "web", "status": "0",
+				"groups":          []interface{}{map[string]interface{}{"groupid": "2"}},
+				"parentTemplates": []interface{}{map[string]interface{}{"templateid": "20", "host": "Template App"}}}}
+		case "template.get":
+			return []interface{}{map[string]interface{}{"templateid": "20", "host": "Template App",
+				"groups": []interface{}{map[string]interface{}{"groupid": "3", "name": "Templates"}}}}
+		case "application.get", "trigger.get":
+			return []interface{}{}
+		case "item.get":
+			if p["inherited"] == false {
+				return []interface{}{
+					map[string]interface{}{"itemid": "30", "hostid": "10", "key_": "app.ping", "name": "Ping", "type": "2", "value_type": "3"},
+					map[string]interface{}{"itemid": "31", "hostid": "20", "key_": "tpl.ping", "name": "Ping", "type": "2", "value_type": "3"},
+				}
+			}
+			return []interface{}{
+				map[string]interface{}{"itemid": "30", "hostid": "10", "key_": "app.ping"},
+				map[string]interface{}{"itemid": "32", "hostid": "10", "key_": "tpl.ping"},
+			}
+		}
+		t.Errorf("Unexpected source call %s", method)
+		return []interface{}{}
+	})
+
+	var calls []string
+	dst := stubAPI(func(method string, params interface{}) interface{} {
+		p, _ := params.(map[string]interface{})
+		switch method {
+		case "hostgroup.get":
+			return []interface{}{map[string]interface{}{"groupid": "7", "name": "Servers"}}
+		case "hostgroup.create":
+			calls = append(calls, fmt.Sprint(method, " ", params))
+			return map[string]interface{}{"groupids": []interface{}{"8"}}
+		case "proxy.get", "template.get", "script.get":
+			return []interface{}{}
+		case "template.create":
+			calls = append(calls, fmt.Sprint(method, " ", p["host"], " ", p["groups"]))
+			return map[string]interface{}{"templateids": []interface{}{"21"}}
+		case "host.get":
+			if p["hostids"] == nil {
+				return []interface{}{}
+			}
+		case "host.create":
+			calls = append(calls, fmt.Sprint(method, " ", p["host"], " ", p["groups"], " ", p["templates"]))
+			return map[string]interface{}{"hostids": []interface{}{"11"}}
+		case "item.create":
+			item := params.([]interface{})[0].(map[string]interface{})
+			calls = append(calls, fmt.Sprint(method, " ", item["hostid"], ":", item["key_"]))
+			if item["hostid"] == "11" {
+				return map[string]interface{}{"itemids": []interface{}{"33"}}
+			}
+			return map[string]interface{}{"itemids": []interface{}{"34"}}
+		case "item.get":
+			return []interface{}{
+				map[string]interface{}{"itemid": "33", "hostid": "11", "key_": "app.ping"},
+				map[string]interface{}{"itemid": "35", "hostid": "11", "key_": "tpl.ping"},
+			}
+		case "trigger.get":
+			return []interface{}{}
+		}
+		t.Errorf("Unexpected destination call %s", method)
+		return []interface{}{}
+	})
+
+	report, err := MigrateHostGroups(src, dst, []string{"Servers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedCalls := []string{
+		"hostgroup.create [map[name:Templates]]",
+		"template.create Template App [map[groupid:8]]",
+		"host.create web [map[groupid:7]] [map[templateid:21]]",
+		"item.create 11:app.ping",
+		"item.create 21:tpl.ping",
+	}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("Expected calls\n%s\ngot\n%s", strings.Join(expectedCalls, "\n"), strings.Join(calls, "\n"))
+	}
+	if groupId, _ := report.Ids.Get("hostgroup", "3"); groupId != "8" {
+		t.Errorf("Bad host group mapping %v", report.Ids)
+	}
+	if templateId, _ := report.Ids.Get("template", "20"); templateId != "21" {
+		t.Errorf("Bad template mapping %v", report.Ids)
+	}
+	expected := "host 10 -> 11\nitem 30 -> 33\nitem 31 -> 34\nitem 32 -> 35\n"
+	if s := report.String(); s != expected {
+		t.Errorf("Bad report:\n%s", s)
+	}
+
+	if _, err = MigrateHostGroups(src, dst, nil); err == nil {
+		t.Error("Expected error for empty names")
+	}
+}
+
+func TestMigrateHostGroups(t *testing.T) {
+	api := getAPI(t)
+
+	group := CreateHostGroup(t)
+	defer DeleteHostGroup(group, t)
+	host := CreateHost(group, t)
+	defer DeleteHost(host, t)
+	app := CreateApplication(host, t)
+	defer DeleteApplication(app, t)
+	item := CreateItem(app, t)
+	defer DeleteItem(item, t)
+
+	// migration to the same server maps all objects to themselves
+	report, err := MigrateHostGroups(api, api, []string{group.Name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := report.Ids.Get("host", host.HostId); id != host.HostId {
+		t.Errorf("Bad host mapping %v", report.Ids)
+	}
+	if id, _ := report.Ids.Get("item", item.ItemId); id != item.ItemId {
+		t.Errorf("Bad item mapping %v", report.Ids)
+	}
+	if len(report.Missing) != 0 {
+		t.Errorf("Unexpected missing objects %v", report.Missing)
+	}
+
+	if _, err = MigrateHostGroups(api, api, []string{group.Name + " missing"}); err == nil {
+		t.Error("Expected error for missing group")
+	}
+}
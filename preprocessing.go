@@ -0,0 +1,189 @@
+package zabbix
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	PreprocessingType         int
+	PreprocessingErrorHandler int
+)
+
+const (
+	PreprocessingMultiplier                PreprocessingType = 1
+	PreprocessingRightTrim                 PreprocessingType = 2
+	PreprocessingLeftTrim                  PreprocessingType = 3
+	PreprocessingTrim                      PreprocessingType = 4
+	PreprocessingRegex                     PreprocessingType = 5
+	PreprocessingBooleanToDecimal          PreprocessingType = 6
+	PreprocessingOctalToDecimal            PreprocessingType = 7
+	PreprocessingHexToDecimal              PreprocessingType = 8
+	PreprocessingSimpleChange              PreprocessingType = 9
+	PreprocessingChangePerSecond           PreprocessingType = 10
+	PreprocessingXMLXPath                  PreprocessingType = 11
+	PreprocessingJSONPath                  PreprocessingType = 12
+	PreprocessingInRange                   PreprocessingType = 13
+	PreprocessingMatchesRegex              PreprocessingType = 14
+	PreprocessingNotMatchesRegex           PreprocessingType = 15
+	PreprocessingCheckJSONError            PreprocessingType = 16
+	PreprocessingCheckXMLError             PreprocessingType = 17
+	PreprocessingCheckRegexError           PreprocessingType = 18
+	PreprocessingDiscardUnchanged          PreprocessingType = 19
+	PreprocessingDiscardUnchangedHeartbeat PreprocessingType = 20
+	PreprocessingJavaScript                PreprocessingType = 21
+	PreprocessingPrometheusPattern         PreprocessingType = 22
+	PreprocessingPrometheusToJSON          PreprocessingType = 23
+	PreprocessingCSVToJSON                 PreprocessingType = 24
+	PreprocessingReplace                   PreprocessingType = 25
+	PreprocessingCheckUnsupported          PreprocessingType = 26
+	PreprocessingXMLToJSON                 PreprocessingType = 27
+
+	// Error handlers
+	PreprocessingErrorDefault      PreprocessingErrorHandler = 0 // set item state to not supported
+	PreprocessingErrorDiscardValue PreprocessingErrorHandler = 1
+	PreprocessingErrorSetValue     PreprocessingErrorHandler = 2 // error_handler_params is the value
+	PreprocessingErrorSetMessage   PreprocessingErrorHandler = 3 // error_handler_params is the message
+)
+
+func PreprocessingTypeToText(aType PreprocessingType) string {
+	switch aType {
+	case PreprocessingMultiplier:
+		return "Custom multiplier"
+	case PreprocessingRightTrim:
+		return "Right trim"
+	case PreprocessingLeftTrim:
+		return "Left trim"
+	case PreprocessingTrim:
+		return "Trim"
+	case PreprocessingRegex:
+		return "Regular expression"
+	case PreprocessingBooleanToDecimal:
+		return "Boolean to decimal"
+	case PreprocessingOctalToDecimal:
+		return "Octal to decimal"
+	case PreprocessingHexToDecimal:
+		return "Hexadecimal to decimal"
+	case PreprocessingSimpleChange:
+		return "Simple change"
+	case PreprocessingChangePerSecond:
+		return "Change per second"
+	case PreprocessingXMLXPath:
+		return "XML XPath"
+	case PreprocessingJSONPath:
+		return "JSONPath"
+	case PreprocessingInRange:
+		return "In range"
+	case PreprocessingMatchesRegex:
+		return "Matches regular expression"
+	case PreprocessingNotMatchesRegex:
+		return "Does not match regular expression"
+	case PreprocessingCheckJSONError:
+		return "Check for error in JSON"
+	case PreprocessingCheckXMLError:
+		return "Check for error in XML"
+	case PreprocessingCheckRegexError:
+		return "Check for error using regular expression"
+	case PreprocessingDiscardUnchanged:
+		return "Discard unchanged"
+	case PreprocessingDiscardUnchangedHeartbeat:
+		return "Discard unchanged with heartbeat"
+	case PreprocessingJavaScript:
+		return "JavaScript"
+	case PreprocessingPrometheusPattern:
+		return "Prometheus pattern"
+	case PreprocessingPrometheusToJSON:
+		return "Prometheus to JSON"
+	case PreprocessingCSVToJSON:
+		return "CSV to JSON"
+	case PreprocessingReplace:
+		return "Replace"
+	case PreprocessingCheckUnsupported:
+		return "Check for not supported value"
+	case PreprocessingXMLToJSON:
+		return "XML to JSON"
+	default:
+		return "Unknown (" + fmt.Sprintf("%d", aType) + ")"
+	}
+}
+
+// https://www.zabbix.com/documentation/4.0/manual/api/reference/item/object#item_preprocessing
+type PreprocessingStep struct {
+	Type               PreprocessingType         `json:"type"`
+	Params             string                    `json:"params"`
+	ErrorHandler       PreprocessingErrorHandler `json:"error_handler"`
+	ErrorHandlerParams string                    `json:"error_handler_params"`
+}
+
+// Steps are applied in slice order. Zabbix replaces all existing steps on item.update.
+type PreprocessingSteps []PreprocessingStep
+
+// Creates preprocessing step with default error handler.
+// Multiple parameters (for example regular expression and output template) are joined with new line as Zabbix expects.
+func NewPreprocessingStep(aType PreprocessingType, params ...string) PreprocessingStep {
+	return PreprocessingStep{Type: aType, Params: strings.Join(params, "\n")}
+}
+
+// Returns step parameters split by new line.
+func (s PreprocessingStep) Parameters() []string {
+	if s.Params == "" {
+		return nil
+	}
+	return strings.Split(s.Params, "\n")
+}
+
+// Returns copy of step which discards value on error.
+func (s PreprocessingStep) OnErrorDiscard() PreprocessingStep {
+	s.ErrorHandler = PreprocessingErrorDiscardValue
+	s.ErrorHandlerParams = ""
+	return s
+}
+
+// Returns copy of step which sets item value to value on error.
+func (s PreprocessingStep) OnErrorSetValue(value string) PreprocessingStep {
+	s.ErrorHandler = PreprocessingErrorSetValue
+	s.ErrorHandlerParams = value
+	return s
+}
+
+// Returns copy of step which sets item error to message on error.
+func (s PreprocessingStep) OnErrorSetMessage(message string) PreprocessingStep {
+	s.ErrorHandler = PreprocessingErrorSetMessage
+	s.ErrorHandlerParams = message
+	return s
+}
+
+// Creates JSONPath step.
+func JSONPathStep(path string) PreprocessingStep {
+	return NewPreprocessingStep(PreprocessingJSONPath, path)
+}
+
+// Creates regular expression step with output template like `\1`.
+func RegexStep(pattern, output string) PreprocessingStep {
+	return NewPreprocessingStep(PreprocessingRegex, pattern, output)
+}
+
+// Creates custom multiplier step.
+func MultiplierStep(multiplier string) PreprocessingStep {
+	return NewPreprocessingStep(PreprocessingMultiplier, multiplier)
+}
+
+// Creates change per second step.
+func ChangePerSecondStep() PreprocessingStep {
+	return NewPreprocessingStep(PreprocessingChangePerSecond)
+}
+
+// Creates JavaScript step.
+func JavaScriptStep(script string) PreprocessingStep {
+	return PreprocessingStep{Type: PreprocessingJavaScript, Params: script}
+}
+
+// Creates Prometheus pattern step. Output is either empty (value) or label name.
+func PrometheusPatternStep(pattern, output string) PreprocessingStep {
+	return NewPreprocessingStep(PreprocessingPrometheusPattern, pattern, output)
+}
+
+// Creates discard unchanged with heartbeat step, heartbeat is time unit like "1h".
+func DiscardUnchangedHeartbeatStep(heartbeat string) PreprocessingStep {
+	return NewPreprocessingStep(PreprocessingDiscardUnchangedHeartbeat, heartbeat)
+}
@@ -0,0 +1,145 @@
+// Zabbix TCP protocol framing used by sender, agents and proxies
+
+package zabbix
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Header flags: https://www.zabbix.com/documentation/4.0/manual/appendix/protocols/header_datalen
+const (
+	ZBXDProtocol   = 0x01
+	ZBXDCompressed = 0x02
+	ZBXDLarge      = 0x04
+)
+
+// Maximum size of packet data accepted by ReadPacket, 128 MiB like in Zabbix server and agent.
+var MaxPacketSize = 128 << 20
+
+var zbxdMagic = []byte("ZBXD")
+
+// Writes data with ZBXD header, compressing it with zlib if compress is set.
+func WritePacket(w io.Writer, data []byte, compress bool) (err error) {
+	flags := byte(ZBXDProtocol)
+	size := len(data)
+	if compress {
+		var b bytes.Buffer
+		z := zlib.NewWriter(&b)
+		if _, err = z.Write(data); err != nil {
+			return
+		}
+		if err = z.Close(); err != nil {
+			return
+		}
+		flags |= ZBXDCompressed
+		data = b.Bytes()
+	}
+
+	var header bytes.Buffer
+	header.Write(zbxdMagic)
+	header.WriteByte(flags)
+	binary.Write(&header, binary.LittleEndian, uint32(len(data)))
+	if compress {
+		binary.Write(&header, binary.LittleEndian, uint32(size))
+	} else {
+		binary.Write(&header, binary.LittleEndian, uint32(0))
+	}
+	_, err = w.Write(append(header.Bytes(), data...))
+	return
+}
+
+// Reads packet written by WritePacket or Zabbix components, decompressing it if needed.
+// Large packets are supported. Data without ZBXD header is returned as is up to newline or EOF,
+// old servers send passive check requests this way.
+func ReadPacket(r io.Reader) (data []byte, err error) {
+	magic := make([]byte, 4)
+	n, err := io.ReadFull(r, magic)
+	if err != nil && !(err == io.ErrUnexpectedEOF && n > 0) {
+		return
+	}
+	if !bytes.Equal(magic[:n], zbxdMagic) {
+		return readLine(r, magic[:n])
+	}
+
+	var flags [1]byte
+	if _, err = io.ReadFull(r, flags[:]); err != nil {
+		return
+	}
+	if flags[0]&ZBXDProtocol == 0 {
+		return nil, fmt.Errorf("Unsupported protocol flags 0x%02x", flags[0])
+	}
+
+	var size, reserved uint64
+	if flags[0]&ZBXDLarge != 0 {
+		err = binary.Read(r, binary.LittleEndian, &size)
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, &reserved)
+		}
+	} else {
+		var size32, reserved32 uint32
+		err = binary.Read(r, binary.LittleEndian, &size32)
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, &reserved32)
+		}
+		size, reserved = uint64(size32), uint64(reserved32)
+	}
+	if err != nil {
+		return
+	}
+	if size > uint64(MaxPacketSize) || reserved > uint64(MaxPacketSize) {
+		return nil, fmt.Errorf("Packet of %d bytes is too large", size)
+	}
+
+	// size comes from peer, so buffer grows with received data instead of being allocated at once
+	if data, err = readAll(r, size); err != nil || flags[0]&ZBXDCompressed == 0 {
+		return
+	}
+
+	z, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	return readAll(z, reserved)
+}
+
+// Reads exactly size bytes from r.
+func readAll(r io.Reader, size uint64) ([]byte, error) {
+	var b bytes.Buffer
+	n, err := b.ReadFrom(io.LimitReader(r, int64(size)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(n) != size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b.Bytes(), nil
+}
+
+// Reads plain text request up to newline, which is not returned.
+func readLine(r io.Reader, data []byte) ([]byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return data[:i], nil
+	}
+	var c [1]byte
+	for len(data) < MaxPacketSize {
+		n, err := r.Read(c[:])
+		if n == 1 {
+			if c[0] == '\n' {
+				return data, nil
+			}
+			data = append(data, c[0])
+		}
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("Packet of %d bytes is too large", len(data))
+}
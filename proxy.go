@@ -10,7 +10,12 @@ type ProxyType int
 
 const (
 	ActiveProxy  ProxyType = 5
-	PassiveProxy ProxyType = 2
+	PassiveProxy ProxyType = 6
+)
+
+// Proxy selectors
+const (
+	SelectInterface = "selectInterface"
 )
 
 // https://www.zabbix.com/documentation/3.2/manual/api/reference/proxy/object
@@ -18,7 +23,12 @@ type Proxy struct {
 	ProxyId     string    `json:"proxyid,omitempty"`
 	Host        string    `json:"host"`
 	Description string    `json:"description,omitempty"`
-	Status      ProxyType `json:"status ,omitempty"`
+	Status      ProxyType `json:"status,omitempty"`
+
+	// Interface of passive proxy, returned with SelectInterface and required when creating passive proxy.
+	// It is HostInterface when creating and object of interface fields, or empty array for active proxy,
+	// when returned.
+	Interface interface{} `json:"interface,omitempty"`
 }
 type Proxys []Proxy
 
@@ -0,0 +1,730 @@
+// desired state reconciler
+
+package zabbix
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Template in desired state. Host is natural key, groups and linked templates are referenced by name.
+type DesiredTemplate struct {
+	Template  Template
+	Groups    []string
+	Templates []string
+	Items     Items    // by Key
+	Triggers  Triggers // by Description and referenced hosts, expressions reference template by its Host
+}
+
+// Host in desired state. Host is natural key, groups and linked templates are referenced by name.
+// Interfaces are used only when host is created.
+type DesiredHost struct {
+	Host      Host
+	Groups    []string
+	Templates []string
+	Items     Items    // by Key, InterfaceId is set to main interface of matching type if empty
+	Triggers  Triggers // by Description and referenced hosts, trigger of several hosts may be listed by each
+}
+
+// Configuration kept in Git and applied by ApplyPlan. Missing host groups are created.
+// Objects which are not mentioned are not touched, except own items and triggers of mentioned hosts
+// and templates, which are deleted if missing.
+// Empty strings and nil enums of objects mean "keep current value", other fields are managed.
+// Nil Groups and Templates of existing hosts and templates are not managed too, while empty non-nil ones
+// remove all groups or unlink all templates.
+type DesiredState struct {
+	HostGroups []string
+	Templates  []DesiredTemplate
+	Hosts      []DesiredHost
+}
+
+type ChangeAction string
+
+const (
+	ChangeCreate ChangeAction = "create"
+	ChangeUpdate ChangeAction = "update"
+	ChangeDelete ChangeAction = "delete"
+)
+
+// Single change of plan. Kind is hostgroup, template, host, item or trigger.
+// Key is natural key like "web01" or "web01:system.cpu.load[all,avg1]", triggers are keyed by hosts
+// they reference like "db01,web01:Replication lag".
+type Change struct {
+	Action ChangeAction
+	Kind   string
+	Key    string
+	Fields []FieldDiff // changed fields of updates
+
+	apply func(r *reconciler) error
+}
+
+func (c *Change) String() string {
+	return fmt.Sprintf("%s %s %s", changeSigns[c.Action], c.Kind, c.Key)
+}
+
+// Changes needed to reach desired state, in order they are applied.
+type Plan struct {
+	Changes []Change
+
+	r *reconciler
+}
+
+// Returns true if current state matches desired.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Returns number of changes with action.
+func (p *Plan) Count(action ChangeAction) (n int) {
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return
+}
+
+// Returns human-readable diff: one line per change, changed fields of updates indented below.
+func (p *Plan) String() string {
+	var b bytes.Buffer
+	for i := range p.Changes {
+		c := &p.Changes[i]
+		writeChange(&b, c.Action, c.Kind, c.Key, c.Fields)
+	}
+	fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d to delete.\n",
+		p.Count(ChangeCreate), p.Count(ChangeUpdate), p.Count(ChangeDelete))
+	return b.String()
+}
+
+// Ids known while planning and applying, by natural keys.
+type reconciler struct {
+	api        *API
+	groups     map[string]string // name to id
+	templates  map[string]string // host to id
+	hosts      map[string]string // host to id
+	interfaces map[string]HostInterfaces
+}
+
+func (r *reconciler) groupIds(names []string) (res HostGroupIds, err error) {
+	for _, name := range names {
+		id := r.groups[name]
+		if id == "" {
+			return nil, fmt.Errorf("Unknown host group %s", name)
+		}
+		res = append(res, HostGroupId{id})
+	}
+	return
+}
+
+func (r *reconciler) templateIds(names []string) (res TemplateIds, err error) {
+	for _, name := range names {
+		id := r.templates[name]
+		if id == "" {
+			return nil, fmt.Errorf("Unknown template %s", name)
+		}
+		res = append(res, TemplateId{id})
+	}
+	return
+}
+
+// Returns id of host or template.
+func (r *reconciler) ownerId(name string) string {
+	if id := r.hosts[name]; id != "" {
+		return id
+	}
+	return r.templates[name]
+}
+
+// Returns main interface of host for item type, or empty string if item doesn't need one.
+func (r *reconciler) interfaceId(host string, itemType ItemType) (string, error) {
+	var want InterfaceType
+	switch fmt.Sprint(itemType) {
+	case "0":
+		want = Agent
+	case "1", "4", "6":
+		want = SNMP
+	case "12":
+		want = IPMI
+	case "16":
+		want = JMX
+	default:
+		return "", nil
+	}
+
+	interfaces, present := r.interfaces[host]
+	if !present {
+		hosts, err := r.api.HostsGet(Params{"hostids": r.hosts[host], "output": []string{"hostid"}, SelectInterfaces: "extend"})
+		if err != nil {
+			return "", err
+		}
+		if len(hosts) == 1 {
+			interfaces = hosts[0].Interfaces
+		}
+		r.interfaces[host] = interfaces
+	}
+	for _, i := range interfaces {
+		if i.Type == want && i.Main == 1 {
+			return i.InterfaceId, nil
+		}
+	}
+	return "", fmt.Errorf("Host %s has no main interface of type %d", host, want)
+}
+
+// Fields of objects which are natural keys, assigned by server or compared separately.
+var (
+	templateIgnored = map[string]bool{"templateid": true, "host": true}
+	hostIgnored     = map[string]bool{"hostid": true, "host": true, "available": true, "error": true}
+	itemIgnored     = map[string]bool{"itemid": true, "hostid": true, "interfaceid": true, "templateid": true, "error": true, "key_": true}
+	triggerIgnored  = map[string]bool{
+		"triggerid": true, "description": true, "expression": true, "recovery_expression": true,
+		"error": true, "lastchange": true, "state": true, "templateid": true, "value": true,
+	}
+)
+
+// Returns expression in canonical form for comparing, or as is if it can't be parsed.
+func normalizeExpression(expression string) string {
+	node, err := ParseExpression(expression)
+	if err != nil {
+		return expression
+	}
+	return node.String()
+}
+
+func sameNames(a, b []string) bool {
+	a, b = append([]string{}, a...), append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return reflect.DeepEqual(a, b) || len(a) == 0 && len(b) == 0
+}
+
+// Orders desired templates so linked templates go before templates linking them.
+func sortDesiredTemplates(templates []DesiredTemplate) (res []DesiredTemplate, err error) {
+	byName := make(map[string]*DesiredTemplate, len(templates))
+	for i := range templates {
+		byName[templates[i].Template.Host] = &templates[i]
+	}
+	state := make(map[string]int)
+	var visit func(name string) error
+	visit = func(name string) error {
+		t := byName[name]
+		if t == nil || state[name] == 2 {
+			return nil
+		}
+		if state[name] == 1 {
+			return fmt.Errorf("Template %s links itself", name)
+		}
+		state[name] = 1
+		for _, parent := range t.Templates {
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		res = append(res, *t)
+		return nil
+	}
+	for _, t := range templates {
+		if err = visit(t.Template.Host); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// Computes changes needed to reach desired state.
+func (api *API) PlanState(state *DesiredState) (plan *Plan, err error) {
+	r := &reconciler{
+		api:        api,
+		groups:     make(map[string]string),
+		templates:  make(map[string]string),
+		hosts:      make(map[string]string),
+		interfaces: make(map[string]HostInterfaces),
+	}
+	plan = &Plan{r: r}
+	templates, err := sortDesiredTemplates(state.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	// host groups
+	groupNames := append([]string{}, state.HostGroups...)
+	for _, t := range templates {
+		groupNames = append(groupNames, t.Groups...)
+	}
+	for _, h := range state.Hosts {
+		groupNames = append(groupNames, h.Groups...)
+	}
+	groups, err := api.HostGroupsGet(Params{"filter": Params{"name": groupNames}})
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		r.groups[g.Name] = g.GroupId
+	}
+	for _, name := range groupNames {
+		if _, present := r.groups[name]; !present {
+			name := name
+			r.groups[name] = "" // created by apply
+			plan.Changes = append(plan.Changes, Change{Action: ChangeCreate, Kind: "hostgroup", Key: name,
+				apply: func(r *reconciler) error {
+					g := HostGroups{{Name: name}}
+					if err := r.api.HostGroupsCreate(g); err != nil {
+						return err
+					}
+					r.groups[name] = g[0].GroupId
+					return nil
+				}})
+		}
+	}
+
+	// templates, including ones linked by desired objects
+	templateNames := []string{}
+	for _, t := range templates {
+		templateNames = append(templateNames, t.Template.Host)
+		templateNames = append(templateNames, t.Templates...)
+	}
+	for _, h := range state.Hosts {
+		templateNames = append(templateNames, h.Templates...)
+	}
+	currentTemplates, err := api.TemplatesGet(Params{
+		"filter":              Params{"host": templateNames},
+		SelectGroups:          []string{"groupid", "name"},
+		SelectParentTemplates: []string{"templateid", "host"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	currentTemplate := make(map[string]*Template)
+	for i, t := range currentTemplates {
+		r.templates[t.Host] = t.TemplateId
+		currentTemplate[t.Host] = &currentTemplates[i]
+	}
+	for _, t := range templates {
+		plan.planTemplate(t, currentTemplate[t.Template.Host])
+	}
+
+	// hosts
+	hostNames := []string{}
+	for _, h := range state.Hosts {
+		hostNames = append(hostNames, h.Host.Host)
+	}
+	currentHosts, err := api.HostsGet(Params{
+		"filter":              Params{"host": hostNames},
+		SelectGroups:          []string{"groupid", "name"},
+		SelectParentTemplates: []string{"templateid", "host"},
+		SelectInterfaces:      "extend",
+	})
+	if err != nil {
+		return nil, err
+	}
+	currentHost := make(map[string]*Host)
+	for i, h := range currentHosts {
+		r.hosts[h.Host] = h.HostId
+		r.interfaces[h.Host] = h.Interfaces
+		currentHost[h.Host] = &currentHosts[i]
+	}
+	for _, h := range state.Hosts {
+		plan.planHost(h, currentHost[h.Host.Host])
+	}
+
+	// items of templates first, so hosts inherit them before own ones are created, triggers after all items
+	deletes := &planDeletes{}
+	var owners []string
+	var triggers []ownedTrigger
+	for _, t := range templates {
+		if err = plan.planItems(t.Template.Host, t.Items, deletes); err != nil {
+			return nil, err
+		}
+		owners = append(owners, t.Template.Host)
+		for _, trigger := range t.Triggers {
+			triggers = append(triggers, ownedTrigger{t.Template.Host, trigger})
+		}
+	}
+	for _, h := range state.Hosts {
+		if err = plan.planItems(h.Host.Host, h.Items, deletes); err != nil {
+			return nil, err
+		}
+		owners = append(owners, h.Host.Host)
+		for _, trigger := range h.Triggers {
+			triggers = append(triggers, ownedTrigger{h.Host.Host, trigger})
+		}
+	}
+	if err = plan.planTriggers(owners, triggers, deletes); err != nil {
+		return nil, err
+	}
+
+	// triggers are deleted before items, since deleting item deletes its triggers
+	plan.Changes = append(plan.Changes, deletes.triggers...)
+	plan.Changes = append(plan.Changes, deletes.items...)
+	return
+}
+
+// Deletes are applied after all other changes.
+type planDeletes struct {
+	triggers []Change
+	items    []Change
+}
+
+// Names of groups and templates of current object. Groups not in desired state are shown by id.
+func currentNames(groups HostGroupIds, groupNames map[string]string, templates Templates) (gs []string, ts []string) {
+	for _, g := range groups {
+		if name := groupNames[g.GroupId]; name != "" {
+			gs = append(gs, name)
+		} else {
+			gs = append(gs, "groupid:"+g.GroupId)
+		}
+	}
+	for _, t := range templates {
+		ts = append(ts, t.Host)
+	}
+	return
+}
+
+func (r *reconciler) groupNames() map[string]string {
+	res := make(map[string]string, len(r.groups))
+	for name, id := range r.groups {
+		res[id] = name
+	}
+	return res
+}
+
+func (p *Plan) planTemplate(desired DesiredTemplate, current *Template) {
+	name := desired.Template.Host
+	if current == nil {
+		p.r.templates[name] = ""
+		p.Changes = append(p.Changes, Change{Action: ChangeCreate, Kind: "template", Key: name,
+			apply: func(r *reconciler) error {
+				params := Params{"host": name}
+				if desired.Template.Name != "" {
+					params["name"] = desired.Template.Name
+				}
+				if desired.Template.Description != "" {
+					params["description"] = desired.Template.Description
+				}
+				groups, err := r.groupIds(desired.Groups)
+				if err != nil {
+					return err
+				}
+				params["groups"] = groups
+				if len(desired.Templates) > 0 {
+					if params["templates"], err = r.templateIds(desired.Templates); err != nil {
+						return err
+					}
+				}
+				response, err := r.api.CallWithError("template.create", params)
+				if err != nil {
+					return err
+				}
+				ids := response.Result.(map[string]interface{})["templateids"].([]interface{})
+				r.templates[name] = ids[0].(string)
+				return nil
+			}})
+		return
+	}
+
+	fields := diffFields(current, &desired.Template, templateIgnored, true)
+	names := p.r.groupNames()
+	var groups HostGroupIds
+	for _, g := range current.Groups {
+		names[g.GroupId] = g.Name
+		groups = append(groups, HostGroupId{g.GroupId})
+	}
+	currentGroups, currentTemplates := currentNames(groups, names, current.ParentTemplates)
+	if desired.Groups != nil && !sameNames(currentGroups, desired.Groups) {
+		fields = append(fields, FieldDiff{"groups", currentGroups, desired.Groups})
+	}
+	if desired.Templates != nil && !sameNames(currentTemplates, desired.Templates) {
+		fields = append(fields, FieldDiff{"templates", currentTemplates, desired.Templates})
+	}
+	if len(fields) == 0 {
+		return
+	}
+	id := current.TemplateId
+	p.Changes = append(p.Changes, Change{Action: ChangeUpdate, Kind: "template", Key: name, Fields: fields,
+		apply: func(r *reconciler) (err error) {
+			params, err := r.updateParams("templateid", id, fields)
+			if err != nil {
+				return
+			}
+			_, err = r.api.TemplatesUpdate(params)
+			return
+		}})
+}
+
+func (p *Plan) planHost(desired DesiredHost, current *Host) {
+	name := desired.Host.Host
+	if current == nil {
+		p.r.hosts[name] = ""
+		p.Changes = append(p.Changes, Change{Action: ChangeCreate, Kind: "host", Key: name,
+			apply: func(r *reconciler) (err error) {
+				host := desired.Host
+				if host.GroupIds, err = r.groupIds(desired.Groups); err != nil {
+					return
+				}
+				if host.Templates, err = r.templateIds(desired.Templates); err != nil {
+					return
+				}
+				hosts := Hosts{host}
+				if err = r.api.HostsCreate(hosts); err != nil {
+					return
+				}
+				r.hosts[name] = hosts[0].HostId
+				return
+			}})
+		return
+	}
+
+	fields := diffFields(current, &desired.Host, hostIgnored, true)
+	currentGroups, currentTemplates := currentNames(current.GroupIds, p.r.groupNames(), current.ParentTemplates)
+	if desired.Groups != nil && !sameNames(currentGroups, desired.Groups) {
+		fields = append(fields, FieldDiff{"groups", currentGroups, desired.Groups})
+	}
+	if desired.Templates != nil && !sameNames(currentTemplates, desired.Templates) {
+		fields = append(fields, FieldDiff{"templates", currentTemplates, desired.Templates})
+	}
+	if len(fields) == 0 {
+		return
+	}
+	id := current.HostId
+	p.Changes = append(p.Changes, Change{Action: ChangeUpdate, Kind: "host", Key: name, Fields: fields,
+		apply: func(r *reconciler) (err error) {
+			params, err := r.updateParams("hostid", id, fields)
+			if err != nil {
+				return
+			}
+			_, err = r.api.CallWithError("host.update", params)
+			return
+		}})
+}
+
+// Returns update params with changed fields, group and template names are replaced with ids.
+func (r *reconciler) updateParams(idField, id string, fields []FieldDiff) (params Params, err error) {
+	params = Params{idField: id}
+	for _, f := range fields {
+		switch f.Field {
+		case "groups":
+			params["groups"], err = r.groupIds(f.New.([]string))
+		case "templates":
+			params["templates"], err = r.templateIds(f.New.([]string))
+		default:
+			params[f.Field] = f.New
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Plans items of host or template, deletes are collected separately.
+func (p *Plan) planItems(owner string, items Items, deletes *planDeletes) error {
+	var currentItems Items
+	if id := p.r.ownerId(owner); id != "" {
+		var err error
+		currentItems, err = p.r.api.ItemsGet(Params{"hostids": id, "inherited": false, "filter": Params{"flags": 0}})
+		if err != nil {
+			return err
+		}
+	}
+
+	byKey, err := currentItems.ByNormalizedKey()
+	if err != nil {
+		return fmt.Errorf("%s: %s", owner, err)
+	}
+	seen := make(map[string]bool)
+	for _, item := range items {
+		key := NormalizeItemKey(item.Key)
+		if seen[key] {
+			return fmt.Errorf("%s: duplicate item key %s", owner, key)
+		}
+		seen[key] = true
+		current, present := byKey[key]
+		p.planItem(owner, item, current, present)
+	}
+	for key, item := range byKey {
+		if !seen[key] {
+			id := item.ItemId
+			deletes.items = append(deletes.items, Change{Action: ChangeDelete, Kind: "item", Key: owner + ":" + key,
+				apply: func(r *reconciler) error { return r.api.ItemsDeleteByIds([]string{id}) }})
+		}
+	}
+
+	return nil
+}
+
+// Desired trigger with host or template listing it.
+type ownedTrigger struct {
+	owner   string
+	trigger Trigger
+}
+
+// Returns natural key of trigger: sorted names of hosts it references and description, like "db,web:Replication lag".
+func triggerKey(hosts []string, description string) string {
+	hosts = append([]string{}, hosts...)
+	sort.Strings(hosts)
+	return strings.Join(hosts, ",") + ":" + description
+}
+
+// Returns names of hosts referenced by expressions, references to current host (5.4+ syntax) mean owner.
+func expressionHosts(owner string, expressions ...string) (res []string) {
+	for _, e := range expressions {
+		node, err := ParseExpression(e)
+		if e == "" || err != nil {
+			continue
+		}
+		for _, ref := range ExpressionItemRefs(node) {
+			host := ref.Host
+			if host == "" {
+				host = owner
+			}
+			if host != "*" && !containsId(res, host) {
+				res = append(res, host)
+			}
+		}
+	}
+	if len(res) == 0 {
+		res = append(res, owner)
+	}
+	return
+}
+
+// Plans triggers of all desired hosts and templates together, so trigger referencing several of them
+// is planned once. Such trigger may be listed by each host, deletes are collected separately.
+func (p *Plan) planTriggers(owners []string, triggers []ownedTrigger, deletes *planDeletes) error {
+	var ids []string
+	for _, owner := range owners {
+		if id := p.r.ownerId(owner); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	var currentKeys []string
+	current := make(map[string]Trigger)
+	if len(ids) > 0 {
+		// only expressions are expanded, descriptions and comments are compared with macros as desired ones
+		currentTriggers, err := p.r.api.TriggersGet(Params{
+			"hostids":        ids,
+			"inherited":      false,
+			"filter":         Params{"flags": 0},
+			SelectHosts:      []string{"host"},
+			expandExpression: true,
+		})
+		if err != nil {
+			return err
+		}
+		for _, t := range currentTriggers {
+			hosts := make([]string, len(t.Hosts))
+			for i, h := range t.Hosts {
+				hosts[i] = h.Host
+			}
+			key := triggerKey(hosts, t.Description)
+			if _, present := current[key]; present {
+				return fmt.Errorf("Duplicate trigger %s on server", key)
+			}
+			t.Hosts = nil
+			current[key] = t
+			currentKeys = append(currentKeys, key)
+		}
+	}
+
+	seen := make(map[string]Trigger)
+	for _, t := range triggers {
+		key := triggerKey(expressionHosts(t.owner, t.trigger.Expression, t.trigger.Recovery_expression), t.trigger.Description)
+		if prev, present := seen[key]; present {
+			if !reflect.DeepEqual(prev, t.trigger) {
+				return fmt.Errorf("Duplicate trigger %s", key)
+			}
+			continue
+		}
+		seen[key] = t.trigger
+		c, present := current[key]
+		p.planTrigger(key, t.trigger, c, present)
+	}
+	for _, key := range currentKeys {
+		if _, present := seen[key]; !present {
+			id := current[key].TriggerId
+			deletes.triggers = append(deletes.triggers, Change{Action: ChangeDelete, Kind: "trigger", Key: key,
+				apply: func(r *reconciler) error { return r.api.TriggersDeleteByIds([]string{id}) }})
+		}
+	}
+	return nil
+}
+
+func (p *Plan) planItem(owner string, desired Item, current Item, present bool) {
+	key := owner + ":" + NormalizeItemKey(desired.Key)
+	if !present {
+		p.Changes = append(p.Changes, Change{Action: ChangeCreate, Kind: "item", Key: key,
+			apply: func(r *reconciler) (err error) {
+				item := desired
+				item.HostId = r.ownerId(owner)
+				if item.InterfaceId == "" && r.hosts[owner] != "" {
+					if item.InterfaceId, err = r.interfaceId(owner, item.Type); err != nil {
+						return
+					}
+				}
+				return r.api.ItemsCreate(Items{item})
+			}})
+		return
+	}
+
+	fields := diffFields(&current, &desired, itemIgnored, true)
+	if len(fields) == 0 {
+		return
+	}
+	id := current.ItemId
+	p.Changes = append(p.Changes, Change{Action: ChangeUpdate, Kind: "item", Key: key, Fields: fields,
+		apply: func(r *reconciler) error {
+			params, err := r.updateParams("itemid", id, fields)
+			if err != nil {
+				return err
+			}
+			delete(params, "itemid")
+			return r.api.ItemsUpdateByIds([]string{id}, params)
+		}})
+}
+
+func (p *Plan) planTrigger(key string, desired Trigger, current Trigger, present bool) {
+	if !present {
+		p.Changes = append(p.Changes, Change{Action: ChangeCreate, Kind: "trigger", Key: key,
+			apply: func(r *reconciler) error { return r.api.TriggersCreate(Triggers{desired}) }})
+		return
+	}
+
+	fields := diffFields(&current, &desired, triggerIgnored, true)
+	if e := normalizeExpression(desired.Expression); e != normalizeExpression(current.Expression) {
+		fields = append(fields, FieldDiff{"expression", current.Expression, desired.Expression})
+	}
+	if desired.Recovery_expression != "" {
+		if e := normalizeExpression(desired.Recovery_expression); e != normalizeExpression(current.Recovery_expression) {
+			fields = append(fields, FieldDiff{"recovery_expression", current.Recovery_expression, desired.Recovery_expression})
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+	id := current.TriggerId
+	p.Changes = append(p.Changes, Change{Action: ChangeUpdate, Kind: "trigger", Key: key, Fields: fields,
+		apply: func(r *reconciler) (err error) {
+			params, err := r.updateParams("triggerid", id, fields)
+			if err != nil {
+				return
+			}
+			_, err = r.api.CallWithError("trigger.update", params)
+			return
+		}})
+}
+
+// Applies plan made by PlanState in order. Stops on first error, changes applied before it are kept.
+func (api *API) ApplyPlan(plan *Plan) error {
+	r := plan.r
+	r.api = api
+	for i := range plan.Changes {
+		c := &plan.Changes[i]
+		if err := c.apply(r); err != nil {
+			return fmt.Errorf("Can't %s %s %s: %s", c.Action, c.Kind, c.Key, err)
+		}
+	}
+	return nil
+}
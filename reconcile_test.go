@@ -0,0 +1,194 @@
+package zabbix_test
+
+import (
+	. "."
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	api := getAPI(t)
+
+	groupName := fmt.Sprintf("Reconciled group %d", rand.Int())
+	hostName := fmt.Sprintf("%s-reconciled-%d", getHost(), rand.Int())
+	state := &DesiredState{
+		Hosts: []DesiredHost{{
+			Host: Host{
+				Host:       hostName,
+				Interfaces: HostInterfaces{{DNS: hostName, Port: "42", Type: Agent, Main: 1}},
+			},
+			Groups: []string{groupName},
+			Items: Items{
+				{Key: "reconciled.trapper", Name: "Trapper", Type: ZabbixTrapper, ValueType: Unsigned},
+				{Key: "reconciled.agent[a]", Name: "Agent", Type: ZabbixAgent, ValueType: Float, Delay: "1m"},
+			},
+			Triggers: Triggers{{Description: "Reconciled trigger", Expression: fmt.Sprintf("{%s:reconciled.trapper.last()}>0", hostName)}},
+		}},
+	}
+
+	plan, err := api.PlanState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Count(ChangeCreate) != 5 || plan.Count(ChangeUpdate) != 0 {
+		t.Fatalf("Bad plan:\n%s", plan)
+	}
+	if err = api.ApplyPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		host, err := api.HostGetByHost(hostName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		DeleteHost(host, t)
+		groups, err := api.HostGroupsGet(Params{"filter": Params{"name": groupName}})
+		if err != nil || len(groups) != 1 {
+			t.Fatal(groups, err)
+		}
+		DeleteHostGroup(&groups[0], t)
+	}()
+
+	plan, err = api.PlanState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Fatalf("Expected empty plan:\n%s", plan)
+	}
+
+	state.Hosts[0].Items[1].Delay = "30s"
+	state.Hosts[0].Items = state.Hosts[0].Items[1:]
+	state.Hosts[0].Triggers = nil
+	plan, err = api.PlanState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := plan.String()
+	for _, expected := range []string{
+		"~ item " + hostName + ":reconciled.agent[a]",
+		`delay: "1m" -> "30s"`,
+		"- trigger " + hostName + ":Reconciled trigger",
+		"- item " + hostName + ":reconciled.trapper",
+	} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("Expected %q in plan:\n%s", expected, s)
+		}
+	}
+	if err = api.ApplyPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlanStateTemplateCycle(t *testing.T) {
+	api := stubAPI(func(method string, params interface{}) interface{} {
+		t.Errorf("Unexpected call %s", method)
+		return []interface{}{}
+	})
+	state := &DesiredState{Templates: []DesiredTemplate{
+		{Template: Template{Host: "Template A"}, Templates: []string{"Template B"}},
+		{Template: Template{Host: "Template B"}, Templates: []string{"Template A"}},
+	}}
+	if _, err := api.PlanState(state); err == nil || !strings.Contains(err.Error(), "links itself") {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+}
+
+func TestPlanStateOrder(t *testing.T) {
+	api := stubAPI(func(method string, params interface{}) interface{} {
+		p := params.(map[string]interface{})
+		switch method {
+		case "hostgroup.get":
+			return []interface{}{map[string]interface{}{"groupid": "1", "name": "Servers"}}
+		case "template.get":
+			return []interface{}{}
+		case "host.get":
+			return []interface{}{
+				map[string]interface{}{"hostid": "10", "host": "web", "groups": []interface{}{map[string]interface{}{"groupid": "1"}}},
+				map[string]interface{}{"hostid": "11", "host": "db", "groups": []interface{}{map[string]interface{}{"groupid": "2"}}},
+			}
+		case "item.get":
+			if p["hostids"] == "10" {
+				return []interface{}{map[string]interface{}{"itemid": "20", "hostid": "10", "key_": "old.item"}}
+			}
+			return []interface{}{}
+		case "trigger.get":
+			return []interface{}{
+				map[string]interface{}{"triggerid": "30", "description": "Replication lag", "expression": "{db:lag.last()}>{web:lag.last()}",
+					"hosts": []interface{}{map[string]interface{}{"host": "db"}, map[string]interface{}{"host": "web"}}},
+				map[string]interface{}{"triggerid": "31", "description": "Old trigger", "expression": "{web:old.item.last()}>0",
+					"hosts": []interface{}{map[string]interface{}{"host": "web"}}},
+			}
+		}
+		t.Errorf("Unexpected call %s", method)
+		return []interface{}{}
+	})
+
+	lag := Trigger{Description: "Replication lag", Expression: "{db:lag.last()} > {web:lag.last()}"}
+	state := &DesiredState{
+		HostGroups: []string{"Servers"},
+		Templates: []DesiredTemplate{
+			{Template: Template{Host: "Template B"}, Groups: []string{"Templates"}, Templates: []string{"Template A"}},
+			{Template: Template{Host: "Template A"}, Groups: []string{"Templates"}},
+		},
+		Hosts: []DesiredHost{
+			{Host: Host{Host: "web"}, Items: Items{{Key: "new.item", Type: ZabbixTrapper, ValueType: Unsigned}}, Triggers: Triggers{lag}},
+			{Host: Host{Host: "db"}, Groups: []string{"Servers"}, Triggers: Triggers{lag}},
+			{Host: Host{Host: "app"}, Groups: []string{"Servers"}},
+		},
+	}
+	plan, err := api.PlanState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `+ hostgroup Templates
++ template Template A
++ template Template B
+~ host db
+    groups: []string{"groupid:2"} -> []string{"Servers"}
++ host app
++ item web:new.item
+- trigger web:Old trigger
+- item web:old.item
+Plan: 5 to create, 1 to update, 2 to delete.
+`
+	if s := plan.String(); s != expected {
+		t.Errorf("Expected plan:\n%s\ngot:\n%s", expected, s)
+	}
+}
+
+func TestPlanStateTriggerMacros(t *testing.T) {
+	api := stubAPI(func(method string, params interface{}) interface{} {
+		p := params.(map[string]interface{})
+		switch method {
+		case "hostgroup.get", "template.get", "item.get":
+			return []interface{}{}
+		case "host.get":
+			return []interface{}{map[string]interface{}{"hostid": "10", "host": "web"}}
+		case "trigger.get":
+			description, comments := "High CPU on {HOST.NAME}", "See {$RUNBOOK}"
+			if p["expandDescription"] == true || p["expandComment"] == true {
+				description, comments = "High CPU on web", "See https://runbook"
+			}
+			return []interface{}{map[string]interface{}{"triggerid": "30", "description": description,
+				"comments": comments, "expression": "{web:system.cpu.load.last()}>5",
+				"hosts": []interface{}{map[string]interface{}{"host": "web"}}}}
+		}
+		t.Errorf("Unexpected call %s", method)
+		return []interface{}{}
+	})
+	state := &DesiredState{Hosts: []DesiredHost{{
+		Host: Host{Host: "web"},
+		Triggers: Triggers{{Description: "High CPU on {HOST.NAME}", Comments: "See {$RUNBOOK}",
+			Expression: "{web:system.cpu.load.last()}>5"}},
+	}}}
+	plan, err := api.PlanState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Errorf("Expected empty plan:\n%s", plan)
+	}
+}
@@ -0,0 +1,173 @@
+// zabbix_sender protocol for trapper items
+
+package zabbix
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Value sent to trapper item. Zero Clock means the server will use time of receiving.
+type SenderValue struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Clock int64  `json:"clock,omitempty"`
+	Ns    int    `json:"ns,omitempty"`
+}
+
+// Creates value with timestamp t, zero t is not sent. Value is formatted with fmt.Sprint.
+func NewSenderValue(host, key string, value interface{}, t time.Time) SenderValue {
+	v := SenderValue{Host: host, Key: key, Value: fmt.Sprint(value)}
+	if !t.IsZero() {
+		v.Clock = t.Unix()
+		v.Ns = t.Nanosecond()
+	}
+	return v
+}
+
+// Result of sending values, summed over all batches.
+type SenderResponse struct {
+	Response  string  `json:"response"`
+	Info      string  `json:"info"`
+	Processed int     `json:"-"`
+	Failed    int     `json:"-"`
+	Total     int     `json:"-"`
+	Seconds   float64 `json:"-"`
+}
+
+var senderInfoRE = regexp.MustCompile(`processed: (\d+); failed: (\d+); total: (\d+); seconds spent: ([0-9.]+)`)
+
+// Fills counters from Info like "processed: 1; failed: 0; total: 1; seconds spent: 0.000055".
+// Called by Send, useful for responses to other requests like agent data.
+func (r *SenderResponse) ParseInfo() {
+	m := senderInfoRE.FindStringSubmatch(r.Info)
+	if m == nil {
+		return
+	}
+	r.Processed, _ = strconv.Atoi(m[1])
+	r.Failed, _ = strconv.Atoi(m[2])
+	r.Total, _ = strconv.Atoi(m[3])
+	r.Seconds, _ = strconv.ParseFloat(m[4], 64)
+}
+
+// Error returned when server responds with anything but "success".
+type SenderError struct {
+	Response string
+	Info     string
+}
+
+func (e *SenderError) Error() string {
+	return fmt.Sprintf("Sender request failed: %s %s", e.Response, e.Info)
+}
+
+// Default number of values sent in a single request.
+var SenderBatchSize = 250
+
+// Client for Zabbix server or proxy trapper port.
+// https://www.zabbix.com/documentation/4.0/manual/appendix/items/trapper
+type Sender struct {
+	Addr      string        // host:port of server or proxy
+	Timeout   time.Duration // for connecting and whole request, no timeout if zero
+	Compress  bool          // compress requests with zlib, supported since Zabbix 4.0
+	BatchSize int           // values per request, SenderBatchSize if zero
+	TLSConfig *tls.Config   // connect with TLS using certificates if set
+
+	// Dials connection instead of net.Dial, for example TLS with PSK using third party library.
+	Dial func(network, addr string) (net.Conn, error)
+}
+
+// Creates sender for address, port 10051 is used if it is not given.
+func NewSender(addr string) *Sender {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "10051")
+	}
+	return &Sender{Addr: addr}
+}
+
+func (s *Sender) dial() (conn net.Conn, err error) {
+	switch {
+	case s.Dial != nil:
+		conn, err = s.Dial("tcp", s.Addr)
+	case s.TLSConfig != nil:
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: s.Timeout}, "tcp", s.Addr, s.TLSConfig)
+	default:
+		conn, err = net.DialTimeout("tcp", s.Addr, s.Timeout)
+	}
+	if err == nil && s.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.Timeout))
+	}
+	return
+}
+
+// Sends request marshaled to JSON and unmarshals response, using new connection.
+// Used for sender data, agent data, active checks and other trapper requests.
+func (s *Sender) Request(request interface{}, response interface{}) (err error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return
+	}
+	conn, err := s.dial()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if err = WritePacket(conn, data, s.Compress); err != nil {
+		return
+	}
+	data, err = ReadPacket(conn)
+	if err != nil {
+		return
+	}
+	return json.Unmarshal(data, response)
+}
+
+type senderRequest struct {
+	Request string        `json:"request"`
+	Data    []SenderValue `json:"data"`
+	Clock   int64         `json:"clock"`
+	Ns      int           `json:"ns"`
+}
+
+// Sends values in batches of BatchSize. Counters of response are summed over batches,
+// Info and Response are from the last one. Sending stops on first error.
+func (s *Sender) Send(values []SenderValue) (res *SenderResponse, err error) {
+	batch := s.BatchSize
+	if batch <= 0 {
+		batch = SenderBatchSize
+	}
+	res = &SenderResponse{}
+	for start := 0; start < len(values); start += batch {
+		end := start + batch
+		if end > len(values) {
+			end = len(values)
+		}
+		now := time.Now()
+		request := senderRequest{Request: "sender data", Data: values[start:end], Clock: now.Unix(), Ns: now.Nanosecond()}
+		var response SenderResponse
+		if err = s.Request(request, &response); err != nil {
+			return
+		}
+		if response.Response != "success" {
+			return res, &SenderError{response.Response, response.Info}
+		}
+		response.ParseInfo()
+		res.Response, res.Info = response.Response, response.Info
+		res.Processed += response.Processed
+		res.Failed += response.Failed
+		res.Total += response.Total
+		res.Seconds += response.Seconds
+	}
+	return
+}
+
+// Sends single value with current time.
+func (s *Sender) SendValue(host, key string, value interface{}) (*SenderResponse, error) {
+	return s.Send([]SenderValue{NewSenderValue(host, key, value, time.Now())})
+}
@@ -0,0 +1,126 @@
+package zabbix_test
+
+import (
+	. "."
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// Local stand-in for trapper port, handle returns response for each request.
+func serveTrapper(t *testing.T, handle func(request map[string]interface{}) interface{}) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			data, err := ReadPacket(conn)
+			if err != nil {
+				t.Error(err)
+				conn.Close()
+				continue
+			}
+			var request map[string]interface{}
+			if err = json.Unmarshal(data, &request); err != nil {
+				t.Error(err)
+			}
+			data, _ = json.Marshal(handle(request))
+			WritePacket(conn, data, false)
+			conn.Close()
+		}
+	}()
+	return l
+}
+
+func TestPacket(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		var b bytes.Buffer
+		data := bytes.Repeat([]byte("data"), 100)
+		if err := WritePacket(&b, data, compress); err != nil {
+			t.Fatal(err)
+		}
+		if b.Bytes()[4] != byte(ZBXDProtocol) && !compress {
+			t.Errorf("Bad flags %x", b.Bytes()[4])
+		}
+		res, err := ReadPacket(&b)
+		if err != nil || !bytes.Equal(res, data) {
+			t.Errorf("compress %v: bad packet %q (%v)", compress, res, err)
+		}
+	}
+
+	res, err := ReadPacket(bytes.NewBufferString("agent.ping\nrest"))
+	if err != nil || string(res) != "agent.ping" {
+		t.Errorf("Bad plain text request %q (%v)", res, err)
+	}
+
+	large := append([]byte("ZBXD\x05"), 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	res, err = ReadPacket(bytes.NewBuffer(append(large, "test"...)))
+	if err != nil || string(res) != "test" {
+		t.Errorf("Bad large packet %q (%v)", res, err)
+	}
+
+	huge := append([]byte("ZBXD\x05"), 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	if _, err = ReadPacket(bytes.NewBuffer(huge)); err == nil {
+		t.Error("Expected error for packet larger than MaxPacketSize")
+	}
+	short := append([]byte("ZBXD\x01"), 0, 0, 0, 4, 0, 0, 0, 0)
+	if _, err = ReadPacket(bytes.NewBuffer(append(short, "test"...))); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected unexpected EOF for truncated packet, got %v", err)
+	}
+}
+
+func TestSender(t *testing.T) {
+	var requests []map[string]interface{}
+	l := serveTrapper(t, func(request map[string]interface{}) interface{} {
+		requests = append(requests, request)
+		n := len(request["data"].([]interface{}))
+		return Params{"response": "success", "info": fmt.Sprintf("processed: %d; failed: 1; total: %d; seconds spent: 0.5", n-1, n)}
+	})
+	defer l.Close()
+
+	s := NewSender(l.Addr().String())
+	s.BatchSize = 2
+	s.Compress = true
+	s.Timeout = time.Second
+	clock := time.Unix(1500000000, 42)
+	values := []SenderValue{
+		NewSenderValue("h", "a", 1.5, clock),
+		NewSenderValue("h", "b", "text", time.Time{}),
+		NewSenderValue("h", "c", 3, clock),
+	}
+	res, err := s.Send(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Processed != 1 || res.Failed != 2 || res.Total != 3 || res.Seconds != 1 {
+		t.Errorf("Bad response %#v", res)
+	}
+	if len(requests) != 2 || requests[0]["request"] != "sender data" {
+		t.Fatalf("Bad requests %v", requests)
+	}
+	first := requests[0]["data"].([]interface{})[0].(map[string]interface{})
+	if first["value"] != "1.5" || first["clock"] != float64(1500000000) || first["ns"] != float64(42) {
+		t.Errorf("Bad value %v", first)
+	}
+	if _, present := requests[0]["data"].([]interface{})[1].(map[string]interface{})["clock"]; present {
+		t.Error("Zero clock should not be sent")
+	}
+
+	l2 := serveTrapper(t, func(request map[string]interface{}) interface{} {
+		return Params{"response": "failed", "info": "bad request"}
+	})
+	defer l2.Close()
+	_, err = NewSender(l2.Addr().String()).SendValue("h", "a", 1)
+	if _, ok := err.(*SenderError); !ok {
+		t.Errorf("Expected SenderError, got %v", err)
+	}
+}
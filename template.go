@@ -29,18 +29,18 @@ type Template struct {
 	Description string `json:"description,omitempty"`
 	Name        string `json:"name,omitempty"`
 	// Extended fields
-	Groups          HostGroups `json:"groups,omitempty"`
-	Templates       Templates  `json:"templates,omitempty"`
-	Items           Items      `json:"items,omitempty"`
-	Hosts           Hosts      `json:"hosts,omitempty"`
-	ParentTemplates Templates  `json:"parentTemplates,omitempty"`
-	HttpTests       string     `json:"httpTests,omitempty"`
-	Discoveries     string     `json:"discoveries,omitempty"`
-	Triggers        Triggers   `json:"triggers,omitempty"`
-	Graphs          string     `json:"graphs,omitempty"`
-	Applications    string     `json:"applications,omitempty"`
-	Macros          string     `json:"macros,omitempty"`
-	Screens         string     `json:"screens,omitempty"`
+	Groups          HostGroups     `json:"groups,omitempty"`
+	Templates       Templates      `json:"templates,omitempty"`
+	Items           Items          `json:"items,omitempty"`
+	Hosts           Hosts          `json:"hosts,omitempty"`
+	ParentTemplates Templates      `json:"parentTemplates,omitempty"`
+	HttpTests       string         `json:"httpTests,omitempty"`
+	Discoveries     DiscoveryRules `json:"discoveries,omitempty"`
+	Triggers        Triggers       `json:"triggers,omitempty"`
+	Graphs          string         `json:"graphs,omitempty"`
+	Applications    string         `json:"applications,omitempty"`
+	Macros          HostMacros     `json:"macros,omitempty"`
+	Screens         string         `json:"screens,omitempty"`
 }
 type Templates []Template
 
@@ -144,3 +144,75 @@ func (api *API) TemplatesDeleteByIds(ids []string) (err error) {
 	}
 	return
 }
+
+// Returns template ids for given ids.
+func NewTemplateIds(ids ...string) (res TemplateIds) {
+	for _, id := range ids {
+		res = append(res, TemplateId{TemplateId: id})
+	}
+	return
+}
+
+// Objects for template.massadd and template.massupdate.
+type TemplatesMass struct {
+	Templates      TemplateIds  `json:"templates"`
+	Groups         HostGroupIds `json:"groups,omitempty"`
+	Hosts          HostIds      `json:"hosts,omitempty"`           // hosts and templates to link templates to
+	TemplatesLink  TemplateIds  `json:"templates_link,omitempty"`  // templates to link to templates
+	TemplatesClear TemplateIds  `json:"templates_clear,omitempty"` // templates to unlink and clear, massupdate only
+}
+
+// Objects for template.massremove.
+type TemplatesMassRemove struct {
+	TemplateIds      []string `json:"templateids"`
+	GroupIds         []string `json:"groupids,omitempty"`
+	HostIds          []string `json:"hostids,omitempty"`           // hosts and templates to unlink templates from
+	TemplateIdsLink  []string `json:"templateids_link,omitempty"`  // templates to unlink from templates
+	TemplateIdsClear []string `json:"templateids_clear,omitempty"` // templates to unlink and clear from templates
+}
+
+// Wrapper for template.massadd: https://www.zabbix.com/documentation/4.0/manual/api/reference/template/massadd
+func (api *API) TemplatesMassAdd(mass *TemplatesMass) (err error) {
+	return api.templatesMass("template.massadd", mass, len(mass.Templates))
+}
+
+// Wrapper for template.massupdate: https://www.zabbix.com/documentation/4.0/manual/api/reference/template/massupdate
+// Replaces groups, hosts and linked templates with given ones, nil fields are not changed.
+func (api *API) TemplatesMassUpdate(mass *TemplatesMass) (err error) {
+	return api.templatesMass("template.massupdate", mass, len(mass.Templates))
+}
+
+// Wrapper for template.massremove: https://www.zabbix.com/documentation/4.0/manual/api/reference/template/massremove
+func (api *API) TemplatesMassRemove(remove *TemplatesMassRemove) (err error) {
+	return api.templatesMass("template.massremove", remove, len(remove.TemplateIds))
+}
+
+func (api *API) templatesMass(method string, params interface{}, count int) (err error) {
+	response, err := api.CallWithError(method, params)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	templateids, _ := result["templateids"].([]interface{})
+	if count != len(templateids) {
+		err = &ExpectedMore{count, len(templateids)}
+	}
+	return
+}
+
+// Links templates to other templates, so they inherit items, triggers and other entities.
+func (api *API) TemplatesLink(templateIds []string, parentIds []string) (err error) {
+	return api.TemplatesMassAdd(&TemplatesMass{Templates: NewTemplateIds(templateIds...), TemplatesLink: NewTemplateIds(parentIds...)})
+}
+
+// Unlinks parent templates from templates. If clear is set, inherited entities are deleted, otherwise they are kept.
+func (api *API) TemplatesUnlink(templateIds []string, parentIds []string, clear bool) (err error) {
+	remove := &TemplatesMassRemove{TemplateIds: templateIds}
+	if clear {
+		remove.TemplateIdsClear = parentIds
+	} else {
+		remove.TemplateIdsLink = parentIds
+	}
+	return api.TemplatesMassRemove(remove)
+}
@@ -0,0 +1,313 @@
+// template inheritance graph
+
+package zabbix
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Inheritance graph of hosts and templates. Links go from host or template to templates linked to it directly,
+// so graph has hosts as roots and templates without parents as leaves.
+// Zabbix doesn't allow cycles, but graph built from exported or hand-made data may have them.
+type TemplateGraph struct {
+	Hosts     map[string]*Host     // by host id
+	Templates map[string]*Template // by template id
+	Links     map[string][]string  // host or template id to ids of directly linked templates
+
+	items            map[string]*Item    // template items by id
+	triggers         map[string]*Trigger // template triggers by id
+	triggerTemplates map[string]string   // template trigger id to template id
+}
+
+// Creates empty graph.
+func NewTemplateGraph() *TemplateGraph {
+	return &TemplateGraph{
+		Hosts:            make(map[string]*Host),
+		Templates:        make(map[string]*Template),
+		Links:            make(map[string][]string),
+		items:            make(map[string]*Item),
+		triggers:         make(map[string]*Trigger),
+		triggerTemplates: make(map[string]string),
+	}
+}
+
+// Adds host with links from its ParentTemplates.
+func (g *TemplateGraph) AddHost(host Host) {
+	g.Hosts[host.HostId] = &host
+	g.Links[host.HostId] = templateIds(host.ParentTemplates)
+}
+
+// Adds template with links from its ParentTemplates, and its Items and Triggers for origin lookups.
+func (g *TemplateGraph) AddTemplate(template Template) {
+	g.Templates[template.TemplateId] = &template
+	g.Links[template.TemplateId] = templateIds(template.ParentTemplates)
+	for i := range template.Items {
+		item := template.Items[i]
+		item.HostId = template.TemplateId
+		g.items[item.ItemId] = &item
+	}
+	for i := range template.Triggers {
+		trigger := template.Triggers[i]
+		g.triggers[trigger.TriggerId] = &trigger
+		g.triggerTemplates[trigger.TriggerId] = template.TemplateId
+	}
+}
+
+func templateIds(templates Templates) (ids []string) {
+	for _, t := range templates {
+		ids = append(ids, t.TemplateId)
+	}
+	sort.Strings(ids)
+	return
+}
+
+// Builds graph for hosts, getting all templates they inherit with items and triggers.
+func (api *API) TemplateGraphForHosts(hostIds []string) (g *TemplateGraph, err error) {
+	hosts, err := api.HostsGet(Params{
+		"hostids":             hostIds,
+		"output":              []string{"hostid", "host", "name"},
+		SelectParentTemplates: []string{"templateid", "host"},
+	})
+	if err != nil {
+		return
+	}
+
+	g = NewTemplateGraph()
+	var ids []string
+	for _, h := range hosts {
+		g.AddHost(h)
+		ids = append(ids, g.Links[h.HostId]...)
+	}
+	for len(ids) > 0 {
+		var templates Templates
+		templates, err = api.TemplatesGet(Params{
+			"templateids":         ids,
+			"output":              []string{"templateid", "host", "name"},
+			SelectParentTemplates: []string{"templateid", "host"},
+			SelectItems:           []string{"itemid", "key_", "templateid"},
+			SelectTriggers:        []string{"triggerid", "description", "templateid"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		ids = nil
+		for _, t := range templates {
+			g.AddTemplate(t)
+		}
+		for _, t := range templates {
+			for _, p := range g.Links[t.TemplateId] {
+				if _, present := g.Templates[p]; !present && !containsId(ids, p) {
+					ids = append(ids, p)
+				}
+			}
+		}
+	}
+	return
+}
+
+func containsId(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Error returned for cyclic links. Cycle starts and ends with the same id.
+type TemplateCycleError struct {
+	Cycle []string
+}
+
+func (e *TemplateCycleError) Error() string {
+	return fmt.Sprintf("Template link cycle: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// Returns all templates inherited by host or template, nearest first, each one once.
+// Returns TemplateCycleError if there is a cycle.
+func (g *TemplateGraph) Ancestors(id string) (res []string, err error) {
+	// depth first search for cycles
+	state := make(map[string]int) // 1 in progress, 2 done
+	var path []string
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case 1:
+			start := 0
+			for path[start] != id {
+				start++
+			}
+			cycle := append(append([]string{}, path[start:]...), id)
+			return &TemplateCycleError{cycle}
+		case 2:
+			return nil
+		}
+		state[id] = 1
+		path = append(path, id)
+		for _, p := range g.Links[id] {
+			if err := visit(p); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = 2
+		return nil
+	}
+	if err = visit(id); err != nil {
+		return nil, err
+	}
+
+	// breadth first for nearest first order
+	seen := map[string]bool{id: true}
+	queue := []string{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, p := range g.Links[current] {
+			if !seen[p] {
+				seen[p] = true
+				res = append(res, p)
+				queue = append(queue, p)
+			}
+		}
+	}
+	return
+}
+
+// Returns all cycles reachable from hosts and templates of graph, each one once.
+func (g *TemplateGraph) Cycles() (res [][]string) {
+	ids := make([]string, 0, len(g.Links))
+	for id := range g.Links {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		_, err := g.Ancestors(id)
+		if e, ok := err.(*TemplateCycleError); ok {
+			key := cycleKey(e.Cycle)
+			if !seen[key] {
+				seen[key] = true
+				res = append(res, e.Cycle)
+			}
+		}
+	}
+	return
+}
+
+// Returns key of cycle independent of its starting point.
+func cycleKey(cycle []string) string {
+	ids := append([]string{}, cycle[:len(cycle)-1]...)
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+// Returns path of links from host or template to inherited template, including both, or nil if not inherited.
+func (g *TemplateGraph) Chain(fromId, templateId string) []string {
+	prev := map[string]string{fromId: ""}
+	queue := []string{fromId}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == templateId && current != fromId {
+			var chain []string
+			for id := current; id != ""; id = prev[id] {
+				chain = append([]string{id}, chain...)
+			}
+			return chain
+		}
+		for _, p := range g.Links[current] {
+			if _, seen := prev[p]; !seen {
+				prev[p] = current
+				queue = append(queue, p)
+			}
+		}
+	}
+	return nil
+}
+
+// Item key inherited by host from different templates, which Zabbix refuses to link.
+type DuplicateKey struct {
+	HostId      string
+	Key         string
+	TemplateIds []string
+}
+
+func (d DuplicateKey) String() string {
+	return fmt.Sprintf("%s: key %s from templates %s", d.HostId, d.Key, strings.Join(d.TemplateIds, ", "))
+}
+
+// Returns item keys defined in more than one template inherited by host or template.
+// Items inherited from one template through several paths are not duplicates.
+func (g *TemplateGraph) DuplicateKeys(id string) (res []DuplicateKey, err error) {
+	ancestors, err := g.Ancestors(id)
+	if err != nil {
+		return
+	}
+
+	// only own items of templates count, inherited ones have TemplateId
+	sources := make(map[string][]string)
+	var keys []string
+	for _, t := range ancestors {
+		template := g.Templates[t]
+		if template == nil {
+			continue
+		}
+		for _, item := range template.Items {
+			if item.TemplateId != "" && item.TemplateId != "0" {
+				continue
+			}
+			key := NormalizeItemKey(item.Key)
+			if _, present := sources[key]; !present {
+				keys = append(keys, key)
+			}
+			sources[key] = append(sources[key], t)
+		}
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		if len(sources[key]) > 1 {
+			res = append(res, DuplicateKey{HostId: id, Key: key, TemplateIds: sources[key]})
+		}
+	}
+	return
+}
+
+// Returns templates which item is inherited through by TemplateId, nearest first and origin last.
+// Returns nil for own items. Error is returned if some template item is not in graph.
+func (g *TemplateGraph) ItemOrigin(item *Item) (templateIds []string, err error) {
+	for id := item.TemplateId; id != "" && id != "0"; {
+		parent := g.items[id]
+		if parent == nil {
+			return nil, fmt.Errorf("Template item %s is not in graph", id)
+		}
+		if containsId(templateIds, parent.HostId) {
+			return nil, &TemplateCycleError{append(templateIds, parent.HostId)}
+		}
+		templateIds = append(templateIds, parent.HostId)
+		id = parent.TemplateId
+	}
+	return
+}
+
+// Returns templates which trigger is inherited through by TemplateId, nearest first and origin last.
+// Returns nil for own triggers. Error is returned if some template trigger is not in graph.
+func (g *TemplateGraph) TriggerOrigin(trigger *Trigger) (templateIds []string, err error) {
+	for id := trigger.TemplateId; id != "" && id != "0"; {
+		parent := g.triggers[id]
+		if parent == nil {
+			return nil, fmt.Errorf("Template trigger %s is not in graph", id)
+		}
+		templateId := g.triggerTemplates[id]
+		if containsId(templateIds, templateId) {
+			return nil, &TemplateCycleError{append(templateIds, templateId)}
+		}
+		templateIds = append(templateIds, templateId)
+		id = parent.TemplateId
+	}
+	return
+}
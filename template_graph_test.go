@@ -0,0 +1,68 @@
+package zabbix_test
+
+import (
+	. "."
+	"reflect"
+	"testing"
+)
+
+func testTemplateGraph() *TemplateGraph {
+	g := NewTemplateGraph()
+	g.AddHost(Host{HostId: "h", Host: "host", ParentTemplates: Templates{{TemplateId: "os"}, {TemplateId: "app"}}})
+	g.AddTemplate(Template{TemplateId: "os", Host: "OS", ParentTemplates: Templates{{TemplateId: "base"}},
+		Items:    Items{{ItemId: "os1", Key: "system.cpu.load[all, avg1]"}, {ItemId: "os2", Key: "agent.ping", TemplateId: "base1"}},
+		Triggers: Triggers{{TriggerId: "ost", TemplateId: "baset"}}})
+	g.AddTemplate(Template{TemplateId: "app", Host: "App", ParentTemplates: Templates{{TemplateId: "base"}},
+		Items: Items{{ItemId: "app1", Key: "system.cpu.load[all,avg1]"}, {ItemId: "app2", Key: "agent.ping", TemplateId: "base1"}}})
+	g.AddTemplate(Template{TemplateId: "base", Host: "Base", Items: Items{{ItemId: "base1", Key: "agent.ping", TemplateId: "0"}},
+		Triggers: Triggers{{TriggerId: "baset", TemplateId: "0"}}})
+	return g
+}
+
+func TestTemplateGraph(t *testing.T) {
+	g := testTemplateGraph()
+
+	ancestors, err := g.Ancestors("h")
+	if err != nil || !reflect.DeepEqual(ancestors, []string{"app", "os", "base"}) {
+		t.Errorf("Bad ancestors %v (%v)", ancestors, err)
+	}
+	if chain := g.Chain("h", "base"); !reflect.DeepEqual(chain, []string{"h", "app", "base"}) {
+		t.Errorf("Bad chain %v", chain)
+	}
+	if chain := g.Chain("os", "app"); chain != nil {
+		t.Errorf("Unexpected chain %v", chain)
+	}
+
+	duplicates, err := g.DuplicateKeys("h")
+	if err != nil || len(duplicates) != 1 || duplicates[0].Key != "system.cpu.load[all,avg1]" ||
+		!reflect.DeepEqual(duplicates[0].TemplateIds, []string{"app", "os"}) {
+		t.Errorf("Bad duplicates %v (%v)", duplicates, err)
+	}
+
+	origin, err := g.ItemOrigin(&Item{Key: "agent.ping", HostId: "h", TemplateId: "os2"})
+	if err != nil || !reflect.DeepEqual(origin, []string{"os", "base"}) {
+		t.Errorf("Bad item origin %v (%v)", origin, err)
+	}
+	origin, err = g.TriggerOrigin(&Trigger{TemplateId: "ost"})
+	if err != nil || !reflect.DeepEqual(origin, []string{"os", "base"}) {
+		t.Errorf("Bad trigger origin %v (%v)", origin, err)
+	}
+	if origin, err = g.ItemOrigin(&Item{TemplateId: "0"}); origin != nil || err != nil {
+		t.Errorf("Own item should have no origin: %v (%v)", origin, err)
+	}
+	if _, err = g.ItemOrigin(&Item{TemplateId: "unknown"}); err == nil {
+		t.Error("Expected error for unknown template item")
+	}
+
+	if cycles := g.Cycles(); len(cycles) != 0 {
+		t.Errorf("Unexpected cycles %v", cycles)
+	}
+	g.AddTemplate(Template{TemplateId: "base", ParentTemplates: Templates{{TemplateId: "os"}}})
+	_, err = g.Ancestors("h")
+	if e, ok := err.(*TemplateCycleError); !ok || len(e.Cycle) != 3 || e.Cycle[0] != e.Cycle[2] {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+	if cycles := g.Cycles(); len(cycles) != 1 {
+		t.Errorf("Expected one cycle, got %v", cycles)
+	}
+}
@@ -0,0 +1,81 @@
+package zabbix_test
+
+import (
+	. "."
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func CreateTemplate(group *HostGroup, t *testing.T) *Template {
+	api := getAPI(t)
+	name := fmt.Sprintf("Template %d", rand.Int())
+	response, err := api.CallWithError("template.create", Params{"host": name, "groups": HostGroupIds{{group.GroupId}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := response.Result.(map[string]interface{})["templateids"].([]interface{})[0].(string)
+	return &Template{TemplateId: id, Host: name}
+}
+
+func DeleteTemplate(template *Template, t *testing.T) {
+	err := getAPI(t).TemplatesDelete(Templates{*template})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTemplatesLink(t *testing.T) {
+	api := getAPI(t)
+
+	group := CreateHostGroup(t)
+	defer DeleteHostGroup(group, t)
+
+	host := CreateHost(group, t)
+	defer DeleteHost(host, t)
+
+	parent := CreateTemplate(group, t)
+	defer DeleteTemplate(parent, t)
+
+	child := CreateTemplate(group, t)
+	defer DeleteTemplate(child, t)
+
+	err := api.TemplatesLink([]string{child.TemplateId}, []string{parent.TemplateId})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.HostsLinkTemplates([]string{host.HostId}, []string{child.TemplateId})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// already inherited through child
+	err = api.HostsLinkTemplates([]string{host.HostId}, []string{parent.TemplateId})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.HostsUnlinkTemplates([]string{host.HostId}, []string{parent.TemplateId})
+	if err == nil {
+		t.Error("Expected error for unlinking nested template")
+	}
+
+	err = api.HostsUnlinkAndClearTemplates([]string{host.HostId}, []string{child.TemplateId})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts, err := api.HostsGet(Params{"hostids": host.HostId, SelectParentTemplates: "extend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 1 || len(hosts[0].ParentTemplates) != 0 {
+		t.Errorf("Templates are not unlinked: %#v", hosts)
+	}
+
+	err = api.TemplatesUnlink([]string{child.TemplateId}, []string{parent.TemplateId}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,255 @@
+// time units and update intervals
+
+package zabbix
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time value like "30s", "1h" or legacy "3600" in seconds, or user or LLD macro like "{$INTERVAL}".
+// https://www.zabbix.com/documentation/4.0/manual/appendix/suffixes
+type TimeUnit struct {
+	Seconds int64
+	Macro   string // not empty if value is macro, Seconds is 0 in this case
+}
+
+var timeSuffixes = []struct {
+	suffix  string
+	seconds int64
+}{{"w", 7 * 86400}, {"d", 86400}, {"h", 3600}, {"m", 60}, {"s", 1}}
+
+var (
+	timeUnitRE  = regexp.MustCompile(`^([0-9]+)([smhdw]?)$`)
+	timeMacroRE = regexp.MustCompile(`^\{(\$[A-Z0-9_.]+(:.*)?|#[A-Z0-9_.]+)\}$`)
+)
+
+// Parses time unit.
+func ParseTimeUnit(s string) (res TimeUnit, err error) {
+	if timeMacroRE.MatchString(s) {
+		res.Macro = s
+		return
+	}
+	m := timeUnitRE.FindStringSubmatch(s)
+	if m == nil {
+		err = fmt.Errorf("Invalid time unit %q", s)
+		return
+	}
+	res.Seconds, err = strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return
+	}
+	for _, ts := range timeSuffixes {
+		if ts.suffix == m[2] {
+			res.Seconds *= ts.seconds
+		}
+	}
+	return
+}
+
+// Creates time unit from duration, rounding it down to seconds.
+func TimeUnitFromDuration(d time.Duration) TimeUnit {
+	return TimeUnit{Seconds: int64(d / time.Second)}
+}
+
+// Returns true if value is macro and can't be known without server.
+func (t TimeUnit) IsMacro() bool {
+	return t.Macro != ""
+}
+
+// Returns duration and true, or false if value is macro.
+func (t TimeUnit) Duration() (time.Duration, bool) {
+	if t.IsMacro() {
+		return 0, false
+	}
+	return time.Duration(t.Seconds) * time.Second, true
+}
+
+// Renders value with the largest suffix which represents it exactly, for example "90s", "2h" or "1w".
+func (t TimeUnit) String() string {
+	if t.IsMacro() {
+		return t.Macro
+	}
+	if t.Seconds == 0 {
+		return "0"
+	}
+	for _, ts := range timeSuffixes {
+		if t.Seconds%ts.seconds == 0 {
+			return strconv.FormatInt(t.Seconds/ts.seconds, 10) + ts.suffix
+		}
+	}
+	return strconv.FormatInt(t.Seconds, 10)
+}
+
+// Custom interval of item update: either flexible like "10s/1-5,09:00-18:00" or scheduling like "wd1-5h9m30".
+// https://www.zabbix.com/documentation/4.0/manual/config/items/item/custom_intervals
+type ItemInterval struct {
+	Delay    TimeUnit // flexible only
+	Period   string   // flexible only, like "1-5,09:00-18:00" or macro
+	Schedule string   // scheduling only
+}
+
+// Returns true for flexible interval.
+func (i ItemInterval) IsFlexible() bool {
+	return i.Period != ""
+}
+
+func (i ItemInterval) String() string {
+	if i.IsFlexible() {
+		return i.Delay.String() + "/" + i.Period
+	}
+	return i.Schedule
+}
+
+// Parsed item update interval like "30s", "1m;10s/1-5,09:00-18:00" or "0;wd1-5h9".
+type ItemDelay struct {
+	Update    TimeUnit
+	Intervals []ItemInterval
+}
+
+var (
+	periodRE   = regexp.MustCompile(`^([1-7])(-([1-7]))?,([0-9]{1,2}):([0-9]{2})-([0-9]{1,2}):([0-9]{2})$`)
+	scheduleRE = regexp.MustCompile(`^(md[0-9,/-]+)?(wd[0-9,/-]+)?(h[0-9,/-]+)?(m[0-9,/-]+)?(s[0-9,/-]+)?$`)
+)
+
+// Parses item update interval.
+func ParseItemDelay(s string) (res *ItemDelay, err error) {
+	parts := strings.Split(s, ";")
+	res = &ItemDelay{}
+	res.Update, err = ParseTimeUnit(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts[1:] {
+		var interval ItemInterval
+		interval, err = parseItemInterval(part)
+		if err != nil {
+			return nil, err
+		}
+		res.Intervals = append(res.Intervals, interval)
+	}
+	return
+}
+
+func parseItemInterval(s string) (res ItemInterval, err error) {
+	if i := strings.IndexByte(s, '/'); i >= 0 && !strings.HasPrefix(s, "md") && !strings.HasPrefix(s, "wd") &&
+		strings.IndexAny(s[:1], "hms") < 0 {
+		res.Delay, err = ParseTimeUnit(s[:i])
+		if err != nil {
+			return
+		}
+		res.Period = s[i+1:]
+		if timeMacroRE.MatchString(res.Period) {
+			return
+		}
+		m := periodRE.FindStringSubmatch(res.Period)
+		if m == nil {
+			err = fmt.Errorf("Invalid flexible interval period %q", res.Period)
+			return
+		}
+		from, to := m[1], m[3]
+		if to == "" {
+			to = from
+		}
+		h1, _ := strconv.Atoi(m[4])
+		m1, _ := strconv.Atoi(m[5])
+		h2, _ := strconv.Atoi(m[6])
+		m2, _ := strconv.Atoi(m[7])
+		if from > to || m1 > 59 || m2 > 59 || h1*60+m1 >= h2*60+m2 || h2*60+m2 > 24*60 {
+			err = fmt.Errorf("Invalid flexible interval period %q", res.Period)
+		}
+		return
+	}
+
+	if s == "" || !scheduleRE.MatchString(s) {
+		err = fmt.Errorf("Invalid custom interval %q", s)
+		return
+	}
+	res.Schedule = s
+	return
+}
+
+func (d *ItemDelay) String() string {
+	parts := []string{d.Update.String()}
+	for _, i := range d.Intervals {
+		parts = append(parts, i.String())
+	}
+	return strings.Join(parts, ";")
+}
+
+// Returns update interval as duration if it is known and there are no custom intervals.
+func (d *ItemDelay) Duration() (time.Duration, bool) {
+	if len(d.Intervals) > 0 {
+		return 0, false
+	}
+	return d.Update.Duration()
+}
+
+// Parses item Delay.
+func (item *Item) ParseDelay() (*ItemDelay, error) {
+	return ParseItemDelay(item.Delay)
+}
+
+// Limits checked by Validate.
+const (
+	maxItemDelay   = 86400
+	minItemHistory = 3600
+	minItemTrends  = 86400
+	maxItemStorage = 25 * 365 * 86400
+)
+
+// Checks syntax and ranges of Delay, History and Trends. Empty values are server defaults and always valid.
+// Range of History and Trends without suffix is not checked, they are days before Zabbix 3.4 and seconds since.
+// Called by ItemsCreate and ItemsUpdate before sending items.
+func (item *Item) Validate() error {
+	if item.Delay != "" {
+		delay, err := item.ParseDelay()
+		if err != nil {
+			return fmt.Errorf("Item %s: %s", item.Key, err)
+		}
+		if delay.Update.Seconds > maxItemDelay {
+			return fmt.Errorf("Item %s: update interval %s is longer than 1d", item.Key, delay.Update)
+		}
+		for _, i := range delay.Intervals {
+			if i.IsFlexible() && i.Delay.Seconds > maxItemDelay {
+				return fmt.Errorf("Item %s: flexible interval %s is longer than 1d", item.Key, i)
+			}
+		}
+	}
+	if err := validateStorage(item.Key, "history", item.History, minItemHistory); err != nil {
+		return err
+	}
+	return validateStorage(item.Key, "trends", item.Trends, minItemTrends)
+}
+
+// Storage period may be 0 (do not keep), or between min and 25 years.
+// Plain numbers are accepted as is, see Validate.
+func validateStorage(key, field, value string, min int64) error {
+	if value == "" {
+		return nil
+	}
+	t, err := ParseTimeUnit(value)
+	if err != nil {
+		return fmt.Errorf("Item %s: %s: %s", key, field, err)
+	}
+	if _, e := strconv.ParseUint(value, 10, 64); e == nil {
+		return nil
+	}
+	if !t.IsMacro() && t.Seconds != 0 && (t.Seconds < min || t.Seconds > maxItemStorage) {
+		return fmt.Errorf("Item %s: %s %s is out of range %s-25y", key, field, t, TimeUnit{Seconds: min})
+	}
+	return nil
+}
+
+// Validates all items, see Item.Validate.
+func (items Items) Validate() error {
+	for i := range items {
+		if err := items[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package zabbix_test
+
+import (
+	. "."
+	"testing"
+	"time"
+)
+
+func TestTimeUnit(t *testing.T) {
+	for s, expected := range map[string]string{
+		"0":            "0",
+		"30":           "30s",
+		"90s":          "90s",
+		"120":          "2m",
+		"3600":         "1h",
+		"1d":           "1d",
+		"14d":          "2w",
+		"{$INTERVAL}":  "{$INTERVAL}",
+		`{$DELAY:"x"}`: `{$DELAY:"x"}`,
+		"{#DELAY}":     "{#DELAY}",
+	} {
+		u, err := ParseTimeUnit(s)
+		if err != nil {
+			t.Errorf("%s: %s", s, err)
+			continue
+		}
+		if u.String() != expected {
+			t.Errorf("%s: expected %s, got %s", s, expected, u)
+		}
+	}
+
+	u, _ := ParseTimeUnit("5m")
+	if d, ok := u.Duration(); !ok || d != 5*time.Minute {
+		t.Errorf("Expected 5m, got %v %v", d, ok)
+	}
+	u, _ = ParseTimeUnit("{$X}")
+	if _, ok := u.Duration(); ok {
+		t.Error("Macro should not be converted to duration")
+	}
+	if s := TimeUnitFromDuration(90 * time.Minute).String(); s != "90m" {
+		t.Errorf("Expected 90m, got %s", s)
+	}
+
+	for _, s := range []string{"", "1y", "-1", "1.5h", "{$lower}", "h"} {
+		if _, err := ParseTimeUnit(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestItemDelay(t *testing.T) {
+	d, err := ParseItemDelay("60;10s/1-5,09:00-18:00;wd1-5h9-18;0/6-7,00:00-24:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Intervals) != 3 || !d.Intervals[0].IsFlexible() || d.Intervals[1].Schedule != "wd1-5h9-18" {
+		t.Errorf("Bad intervals: %#v", d.Intervals)
+	}
+	if s := d.String(); s != "1m;10s/1-5,09:00-18:00;wd1-5h9-18;0/6-7,00:00-24:00" {
+		t.Errorf("Bad string: %s", s)
+	}
+	if _, ok := d.Duration(); ok {
+		t.Error("Delay with custom intervals should not be converted to duration")
+	}
+
+	for _, s := range []string{"1m;", "1m;10s/8,00:00-10:00", "1m;10s/5-1,00:00-10:00", "1m;10s/1,10:00-09:00", "1m;xx1"} {
+		if _, err := ParseItemDelay(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+
+	for _, item := range []Item{
+		{Key: "a", Delay: "2d"},
+		{Key: "b", History: "10m"},
+		{Key: "c", Trends: "26w", History: "30y"},
+		{Key: "d", Delay: "1m;bad"},
+	} {
+		if err := item.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", item.Key)
+		}
+	}
+	// legacy values in days are not checked for range
+	items := Items{{Key: "a", Delay: "{$D}", History: "0", Trends: "365d"}, {Key: "b"}, {Key: "c", History: "90", Trends: "365"}}
+	if err := items.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,204 @@
+package zabbix
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/wOvAN/reflector"
+)
+
+// Hourly trend of numeric float item. Trends returned by trend.get may also be rolled up into longer periods,
+// Clock is start of period in this case.
+// https://www.zabbix.com/documentation/4.0/manual/api/reference/trend/object
+type Trend struct {
+	ItemId   string    `json:"itemid"`
+	Clock    Timestamp `json:"clock"`
+	Num      int       `json:"num"`
+	ValueMin float64   `json:"value_min"`
+	ValueAvg float64   `json:"value_avg"`
+	ValueMax float64   `json:"value_max"`
+}
+
+type Trends []Trend
+
+// Hourly trend of numeric unsigned item.
+type TrendUint struct {
+	ItemId   string    `json:"itemid"`
+	Clock    Timestamp `json:"clock"`
+	Num      int       `json:"num"`
+	ValueMin uint64    `json:"value_min"`
+	ValueAvg uint64    `json:"value_avg"`
+	ValueMax uint64    `json:"value_max"`
+}
+
+type TrendUints []TrendUint
+
+// Wrapper for trend.get: https://www.zabbix.com/documentation/4.0/manual/api/reference/trend/get
+// Use for float items, see TrendsGetUint for unsigned ones.
+func (api *API) TrendsGet(params Params) (res Trends, err error) {
+	err = api.trendsGet(params, &res)
+	return
+}
+
+// Wrapper for trend.get for unsigned items.
+func (api *API) TrendsGetUint(params Params) (res TrendUints, err error) {
+	err = api.trendsGet(params, &res)
+	return
+}
+
+// Gets trends of float items with given ids in time range. Zero from or till is not sent.
+func (api *API) TrendsGetByItemIds(ids []string, from, till time.Time) (res Trends, err error) {
+	return api.TrendsGet(trendParams(ids, from, till))
+}
+
+// Gets trends of unsigned items with given ids in time range. Zero from or till is not sent.
+func (api *API) TrendsGetUintByItemIds(ids []string, from, till time.Time) (res TrendUints, err error) {
+	return api.TrendsGetUint(trendParams(ids, from, till))
+}
+
+func trendParams(ids []string, from, till time.Time) Params {
+	params := Params{"itemids": ids}
+	if !from.IsZero() {
+		params["time_from"] = from.Unix()
+	}
+	if !till.IsZero() {
+		params["time_till"] = till.Unix()
+	}
+	return params
+}
+
+func (api *API) trendsGet(params Params, res interface{}) (err error) {
+	if _, present := params["output"]; !present {
+		params["output"] = "extend"
+	}
+	response, err := api.CallWithError("trend.get", params)
+	if err != nil {
+		return
+	}
+
+	reflector.MapsToStructs2(response.Result.([]interface{}), res, reflector.Strconv, "json")
+	return
+}
+
+// Returns start of day in location.
+func DayStart(loc *time.Location) func(time.Time) time.Time {
+	return func(t time.Time) time.Time {
+		t = t.In(loc)
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+// Returns start of week in location, weeks start on Monday.
+func WeekStart(loc *time.Location) func(time.Time) time.Time {
+	day := DayStart(loc)
+	return func(t time.Time) time.Time {
+		t = day(t)
+		return t.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+	}
+}
+
+type trendBucket struct {
+	itemId string
+	clock  int64
+}
+
+// Sorts buckets by item id and time.
+type trendBuckets []trendBucket
+
+func (b trendBuckets) Len() int      { return len(b) }
+func (b trendBuckets) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b trendBuckets) Less(i, j int) bool {
+	return b[i].itemId < b[j].itemId || b[i].itemId == b[j].itemId && b[i].clock < b[j].clock
+}
+
+// Combines trends into periods returned by bucket for trend Clock, for example DayStart(time.Local).
+// Num is summed, average is weighted by Num. Result is sorted by item id and time.
+func (trends Trends) Rollup(bucket func(time.Time) time.Time) (res Trends) {
+	sums := make(map[trendBucket]*Trend)
+	var keys trendBuckets
+	for _, t := range trends {
+		start := bucket(time.Time(t.Clock))
+		key := trendBucket{t.ItemId, start.Unix()}
+		sum := sums[key]
+		if sum == nil {
+			sum = &Trend{ItemId: t.ItemId, Clock: Timestamp(start), ValueMin: t.ValueMin, ValueMax: t.ValueMax}
+			sums[key] = sum
+			keys = append(keys, key)
+		}
+		if t.ValueMin < sum.ValueMin {
+			sum.ValueMin = t.ValueMin
+		}
+		if t.ValueMax > sum.ValueMax {
+			sum.ValueMax = t.ValueMax
+		}
+		if sum.Num+t.Num > 0 {
+			sum.ValueAvg += (t.ValueAvg - sum.ValueAvg) * float64(t.Num) / float64(sum.Num+t.Num)
+		}
+		sum.Num += t.Num
+	}
+
+	sort.Sort(keys)
+	for _, key := range keys {
+		res = append(res, *sums[key])
+	}
+	return
+}
+
+// Combines trends into days in location.
+func (trends Trends) RollupDaily(loc *time.Location) Trends {
+	return trends.Rollup(DayStart(loc))
+}
+
+// Combines trends into weeks in location.
+func (trends Trends) RollupWeekly(loc *time.Location) Trends {
+	return trends.Rollup(WeekStart(loc))
+}
+
+// Combines trends into periods returned by bucket, see Trends.Rollup.
+// Average is calculated without overflow and rounded down like Zabbix does.
+func (trends TrendUints) Rollup(bucket func(time.Time) time.Time) (res TrendUints) {
+	sums := make(map[trendBucket]*TrendUint)
+	totals := make(map[trendBucket]*big.Int)
+	var keys trendBuckets
+	for _, t := range trends {
+		start := bucket(time.Time(t.Clock))
+		key := trendBucket{t.ItemId, start.Unix()}
+		sum := sums[key]
+		if sum == nil {
+			sum = &TrendUint{ItemId: t.ItemId, Clock: Timestamp(start), ValueMin: t.ValueMin, ValueMax: t.ValueMax}
+			sums[key] = sum
+			totals[key] = new(big.Int)
+			keys = append(keys, key)
+		}
+		if t.ValueMin < sum.ValueMin {
+			sum.ValueMin = t.ValueMin
+		}
+		if t.ValueMax > sum.ValueMax {
+			sum.ValueMax = t.ValueMax
+		}
+		weighted := new(big.Int).SetUint64(t.ValueAvg)
+		totals[key].Add(totals[key], weighted.Mul(weighted, big.NewInt(int64(t.Num))))
+		sum.Num += t.Num
+	}
+
+	sort.Sort(keys)
+	for _, key := range keys {
+		sum := sums[key]
+		if sum.Num > 0 {
+			sum.ValueAvg = totals[key].Div(totals[key], big.NewInt(int64(sum.Num))).Uint64()
+		}
+		res = append(res, *sum)
+	}
+	return
+}
+
+// Combines trends into days in location.
+func (trends TrendUints) RollupDaily(loc *time.Location) TrendUints {
+	return trends.Rollup(DayStart(loc))
+}
+
+// Combines trends into weeks in location.
+func (trends TrendUints) RollupWeekly(loc *time.Location) TrendUints {
+	return trends.Rollup(WeekStart(loc))
+}
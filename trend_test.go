@@ -0,0 +1,43 @@
+package zabbix_test
+
+import (
+	. "."
+	"testing"
+	"time"
+)
+
+func TestTrendsRollup(t *testing.T) {
+	// Monday
+	start := time.Date(2017, 7, 17, 22, 0, 0, 0, time.UTC)
+	hour := func(i int) Timestamp { return Timestamp(start.Add(time.Duration(i) * time.Hour)) }
+	trends := Trends{
+		{ItemId: "1", Clock: hour(0), Num: 60, ValueMin: 1, ValueAvg: 2, ValueMax: 3},
+		{ItemId: "1", Clock: hour(1), Num: 20, ValueMin: 0, ValueAvg: 6, ValueMax: 9},
+		{ItemId: "1", Clock: hour(2), Num: 60, ValueMin: 5, ValueAvg: 5, ValueMax: 5},
+		{ItemId: "0", Clock: hour(1), Num: 60, ValueMin: 1, ValueAvg: 1, ValueMax: 1},
+	}
+
+	daily := trends.RollupDaily(time.UTC)
+	if len(daily) != 3 || daily[0].ItemId != "0" {
+		t.Fatalf("Bad daily trends: %#v", daily)
+	}
+	d := daily[1]
+	if !time.Time(d.Clock).Equal(time.Date(2017, 7, 17, 0, 0, 0, 0, time.UTC)) || d.Num != 80 ||
+		d.ValueMin != 0 || d.ValueAvg != 3 || d.ValueMax != 9 {
+		t.Errorf("Bad daily trend: %#v", d)
+	}
+
+	weekly := trends.RollupWeekly(time.UTC)
+	if len(weekly) != 2 || weekly[1].Num != 140 || !time.Time(weekly[1].Clock).Equal(time.Date(2017, 7, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Bad weekly trends: %#v", weekly)
+	}
+
+	uints := TrendUints{
+		{ItemId: "1", Clock: hour(0), Num: 2, ValueMin: 1, ValueAvg: 18446744073709551615, ValueMax: 18446744073709551615},
+		{ItemId: "1", Clock: hour(1), Num: 2, ValueMin: 1, ValueAvg: 18446744073709551613, ValueMax: 18446744073709551615},
+	}
+	w := uints.RollupWeekly(time.UTC)
+	if len(w) != 1 || w[0].ValueAvg != 18446744073709551614 || w[0].Num != 4 {
+		t.Errorf("Bad unsigned trends: %#v", w)
+	}
+}
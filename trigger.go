@@ -4,7 +4,7 @@ package zabbix
 
 import (
 	"fmt"
-
+	"regexp"
 	"strconv"
 
 	"github.com/wOvAN/reflector"
@@ -27,6 +27,9 @@ const (
 		selectLastEvent 	query 	Return the last significant trigger event in the lastEvent property.
 		selectTags 	query 	Return the trigger tags in tags property.
 	*/
+	SelectFunctions    = "selectFunctions"
+	SelectDependencies = "selectDependencies"
+	SelectTags         = "selectTags"
 
 	// Trigget expandors
 	expandComment     = "expandComment"
@@ -36,28 +39,69 @@ const (
 
 // https://www.zabbix.com/documentation/4.0/manual/api/reference/trigger/object
 type (
-	TriggerPriority interface{}
-	TriggerStatus   interface{}
+	TriggerPriority        interface{}
+	TriggerStatus          interface{}
+	TriggerRecoveryMode    interface{}
+	TriggerCorrelationMode interface{}
+	TriggerManualClose     interface{}
+	TriggerType            interface{}
 
 	Trigger struct {
 		//string `json:",omitempty"`
 		TriggerId   string          `json:"triggerid,omitempty"`
 		Description string          `json:"description"`
-		Expression  string          `json:"expression"`
+		Expression  string          `json:"expression,omitempty"`
 		Comments    string          `json:"comments,omitempty"`
 		Priority    TriggerPriority `json:"priority,omitempty"`
 		Status      TriggerStatus   `json:"status,omitempty"`
+		Type        TriggerType     `json:"type,omitempty"`
+		URL         string          `json:"url,omitempty"`
+
+		RecoveryMode        TriggerRecoveryMode    `json:"recovery_mode,omitempty"`
+		Recovery_expression string                 `json:"recovery_expression,omitempty"`
+		CorrelationMode     TriggerCorrelationMode `json:"correlation_mode,omitempty"`
+		Correlation_tag     string                 `json:"correlation_tag,omitempty"`
+		ManualClose         TriggerManualClose     `json:"manual_close,omitempty"`
+
+		// Read-only fields, never sent by TriggersUpdate
+		Error      string `json:"error,omitempty"`
+		LastChange string `json:"lastchange,omitempty"`
+		State      string `json:"state,omitempty"`
+		TemplateId string `json:"templateid,omitempty"`
+		Value      string `json:"value,omitempty"`
 
-		//	Recovery_mode       recovery_mode
-		Recovery_expression string `json:"recovery_expression,omitempty"`
-		//	Correlation_mode    correlation_mode
-		Correlation_tag string `json:"correlation_tag,omitempty"`
-		// Extended fields
+		// Extended fields, returned by trigger.get only with corresponding selectors
+		Functions TriggerFunctions `json:"functions,omitempty"`
+		Groups    HostGroups       `json:"groups,omitempty"`
+		Hosts     Hosts            `json:"hosts,omitempty"`
+		Items     Items            `json:"items,omitempty"`
 
+		// Returned with SelectDependencies, used when creating or updating triggers
+		Dependencies TriggerIds `json:"dependencies,omitempty"`
+
+		// Returned with SelectTags, used when creating or updating triggers
+		Tags TriggerTags `json:"tags,omitempty"`
 	}
 
 	Triggers []Trigger
 
+	// https://www.zabbix.com/documentation/4.0/manual/api/reference/trigger/get (selectFunctions)
+	TriggerFunction struct {
+		FunctionId string `json:"functionid"`
+		ItemId     string `json:"itemid"`
+		Function   string `json:"function"`
+		Parameter  string `json:"parameter"`
+	}
+
+	TriggerFunctions []TriggerFunction
+
+	TriggerTag struct {
+		Tag   string `json:"tag"`
+		Value string `json:"value"`
+	}
+
+	TriggerTags []TriggerTag
+
 	TriggerId struct {
 		TriggerId string `json:"triggerid"`
 	}
@@ -75,7 +119,20 @@ var (
 	TriggerPriorityDisaster    TriggerPriority = 5
 	// Status
 	TriggerStatusEnabled  TriggerStatus = 0
-	TriggerStatusDisabled TriggerStatus = 0
+	TriggerStatusDisabled TriggerStatus = 1
+	// Recovery mode
+	TriggerRecoveryExpression         TriggerRecoveryMode = 0
+	TriggerRecoveryRecoveryExpression TriggerRecoveryMode = 1
+	TriggerRecoveryNone               TriggerRecoveryMode = 2
+	// Correlation mode
+	TriggerCorrelationAll TriggerCorrelationMode = 0
+	TriggerCorrelationTag TriggerCorrelationMode = 1
+	// Manual close
+	TriggerManualCloseNo  TriggerManualClose = 0
+	TriggerManualCloseYes TriggerManualClose = 1
+	// Type
+	TriggerSingleEvent    TriggerType = 0
+	TriggerMultipleEvents TriggerType = 1
 )
 
 func TriggerPriorityToText(aTriggerPriority TriggerPriority) string {
@@ -132,6 +189,34 @@ func (api *API) TriggersGet(params Params) (res Triggers, err error) {
 	return
 }
 
+// Same as TriggersGet, but description, expression and comments are returned with macros expanded
+// and expression contains host and item key instead of function ids.
+// Such triggers are for display only: updating them replaces macros in description and comments
+// with their values, use TriggersGetWithDetails with expand to get triggers for TriggersUpdate.
+func (api *API) TriggersGetExpanded(params Params) (res Triggers, err error) {
+	params[expandDescription] = true
+	params[expandExpression] = true
+	params[expandComment] = true
+	return api.TriggersGet(params)
+}
+
+// Gets triggers by Ids together with functions, hosts, items and dependencies.
+// If expand is true, expression contains host and item key instead of function ids, while description
+// and comments keep macros, so triggers may be passed to TriggersUpdate.
+func (api *API) TriggersGetWithDetails(ids []string, expand bool) (res Triggers, err error) {
+	params := Params{
+		"triggerids":       ids,
+		SelectFunctions:    "extend",
+		SelectHosts:        []string{"hostid", "host", "name"},
+		SelectItems:        []string{"itemid", "hostid", "key_", "name"},
+		SelectDependencies: []string{"triggerid"},
+	}
+	if expand {
+		params[expandExpression] = true
+	}
+	return api.TriggersGet(params)
+}
+
 // Gets host trigger by Id only if there is exactly 1 matching host trigger.
 func (api *API) TriggerGetById(id string) (res *Trigger, err error) {
 	triggers, err := api.TriggersGet(Params{"triggerids": id})
@@ -163,6 +248,71 @@ func (api *API) TriggersCreate(triggers Triggers) (err error) {
 	return
 }
 
+// Matches expression in form returned by trigger.get without expandExpression, like "{13055}>0".
+var functionIdRE = regexp.MustCompile(`\{\d+\}`)
+
+// Wrapper for trigger.update: https://www.zabbix.com/documentation/4.0/manual/api/reference/trigger/update
+// Read-only and extended fields are not sent. Expressions which refer to function ids
+// (as returned by TriggersGet without expanding) are not sent too, so triggers may be updated right after getting.
+// Triggers got by TriggersGetExpanded must not be updated, macros in their description and comments are lost.
+func (api *API) TriggersUpdate(triggers Triggers) (err error) {
+	objects := make(Triggers, len(triggers))
+	for i, trigger := range triggers {
+		if functionIdRE.MatchString(trigger.Expression) {
+			trigger.Expression = ""
+		}
+		if functionIdRE.MatchString(trigger.Recovery_expression) {
+			trigger.Recovery_expression = ""
+		}
+		trigger.Error = ""
+		trigger.LastChange = ""
+		trigger.State = ""
+		trigger.TemplateId = ""
+		trigger.Value = ""
+		trigger.Functions = nil
+		trigger.Groups = nil
+		trigger.Hosts = nil
+		trigger.Items = nil
+		objects[i] = trigger
+	}
+
+	response, err := api.CallWithError("trigger.update", objects)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	triggerids := result["triggerids"].([]interface{})
+	if len(triggers) != len(triggerids) {
+		err = &ExpectedMore{len(triggers), len(triggerids)}
+	}
+	return
+}
+
+// Wrapper for trigger.adddependencies: https://www.zabbix.com/documentation/4.0/manual/api/reference/trigger/adddependencies
+// Makes trigger with triggerId depend on all triggers with dependsOnIds.
+func (api *API) TriggersAddDependencies(triggerId string, dependsOnIds []string) (err error) {
+	params := make([]map[string]string, len(dependsOnIds))
+	for i, id := range dependsOnIds {
+		params[i] = map[string]string{"triggerid": triggerId, "dependsOnTriggerid": id}
+	}
+
+	_, err = api.CallWithError("trigger.adddependencies", params)
+	return
+}
+
+// Wrapper for trigger.deletedependencies: https://www.zabbix.com/documentation/4.0/manual/api/reference/trigger/deletedependencies
+// Removes all dependencies of triggers with given ids.
+func (api *API) TriggersDeleteDependencies(ids []string) (err error) {
+	params := make([]map[string]string, len(ids))
+	for i, id := range ids {
+		params[i] = map[string]string{"triggerid": id}
+	}
+
+	_, err = api.CallWithError("trigger.deletedependencies", params)
+	return
+}
+
 // Wrapper for trigger.delete: https://www.zabbix.com/documentation/2.2/manual/appendix/api/trigger/delete
 // Cleans TriggerId in all triggers elements if call succeed.
 func (api *API) TriggersDelete(triggers Triggers) (err error) {
@@ -0,0 +1,110 @@
+// trigger prototypes
+
+package zabbix
+
+import (
+	"github.com/wOvAN/reflector"
+)
+
+// https://www.zabbix.com/documentation/5.0/manual/api/reference/triggerprototype/object
+type TriggerPrototype struct {
+	TriggerId          string          `json:"triggerid,omitempty"`
+	Description        string          `json:"description"`
+	Expression         string          `json:"expression"`
+	Comments           string          `json:"comments,omitempty"`
+	Priority           TriggerPriority `json:"priority,omitempty"`
+	Status             TriggerStatus   `json:"status,omitempty"`
+	RecoveryExpression string          `json:"recovery_expression,omitempty"`
+	URL                string          `json:"url,omitempty"`
+	TemplateId         string          `json:"templateid,omitempty"`
+
+	// Fields below used only when creating trigger prototypes
+	Dependencies TriggerIds `json:"dependencies,omitempty"`
+}
+
+type TriggerPrototypes []TriggerPrototype
+
+// Returns ids of all trigger prototypes.
+func (prototypes TriggerPrototypes) Ids() (res []string) {
+	res = make([]string, len(prototypes))
+	for i, prototype := range prototypes {
+		res[i] = prototype.TriggerId
+	}
+	return
+}
+
+// Wrapper for triggerprototype.get: https://www.zabbix.com/documentation/5.0/manual/api/reference/triggerprototype/get
+func (api *API) TriggerPrototypesGet(params Params) (res TriggerPrototypes, err error) {
+	if _, present := params["output"]; !present {
+		params["output"] = "extend"
+	}
+	response, err := api.CallWithError("triggerprototype.get", params)
+	if err != nil {
+		return
+	}
+
+	reflector.MapsToStructs2(response.Result.([]interface{}), &res, reflector.Strconv, "json")
+	return
+}
+
+// Gets trigger prototypes of discovery rule.
+func (api *API) TriggerPrototypesGetByRuleId(ruleId string) (res TriggerPrototypes, err error) {
+	return api.TriggerPrototypesGet(Params{"discoveryids": ruleId})
+}
+
+// Wrapper for triggerprototype.create: https://www.zabbix.com/documentation/5.0/manual/api/reference/triggerprototype/create
+func (api *API) TriggerPrototypesCreate(prototypes TriggerPrototypes) (err error) {
+	response, err := api.CallWithError("triggerprototype.create", prototypes)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	triggerids := result["triggerids"].([]interface{})
+	for i, id := range triggerids {
+		prototypes[i].TriggerId = id.(string)
+	}
+	return
+}
+
+// Wrapper for triggerprototype.update: https://www.zabbix.com/documentation/5.0/manual/api/reference/triggerprototype/update
+func (api *API) TriggerPrototypesUpdate(prototypes TriggerPrototypes) (err error) {
+	response, err := api.CallWithError("triggerprototype.update", prototypes)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	triggerids := result["triggerids"].([]interface{})
+	if len(prototypes) != len(triggerids) {
+		err = &ExpectedMore{len(prototypes), len(triggerids)}
+	}
+	return
+}
+
+// Wrapper for triggerprototype.delete: https://www.zabbix.com/documentation/5.0/manual/api/reference/triggerprototype/delete
+// Cleans TriggerId in all prototypes elements if call succeed.
+func (api *API) TriggerPrototypesDelete(prototypes TriggerPrototypes) (err error) {
+	err = api.TriggerPrototypesDeleteByIds(prototypes.Ids())
+	if err == nil {
+		for i := range prototypes {
+			prototypes[i].TriggerId = ""
+		}
+	}
+	return
+}
+
+// Wrapper for triggerprototype.delete: https://www.zabbix.com/documentation/5.0/manual/api/reference/triggerprototype/delete
+func (api *API) TriggerPrototypesDeleteByIds(ids []string) (err error) {
+	response, err := api.CallWithError("triggerprototype.delete", ids)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	triggerids := result["triggerids"].([]interface{})
+	if len(ids) != len(triggerids) {
+		err = &ExpectedMore{len(ids), len(triggerids)}
+	}
+	return
+}
@@ -0,0 +1,122 @@
+package zabbix_test
+
+import (
+	. "."
+	"testing"
+)
+
+func CreateTrigger(item *Item, host *Host, t *testing.T) *Trigger {
+	triggers := Triggers{{
+		Description: "Trigger for " + item.Key,
+		Expression:  "{" + host.Host + ":" + item.Key + ".last()}=0",
+		Priority:    TriggerPriorityWarning,
+	}}
+	err := getAPI(t).TriggersCreate(triggers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &triggers[0]
+}
+
+func DeleteTrigger(trigger *Trigger, t *testing.T) {
+	err := getAPI(t).TriggersDelete(Triggers{*trigger})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	api := getAPI(t)
+
+	group := CreateHostGroup(t)
+	defer DeleteHostGroup(group, t)
+
+	host := CreateHost(group, t)
+	defer DeleteHost(host, t)
+
+	app := CreateApplication(host, t)
+	defer DeleteApplication(app, t)
+
+	item := CreateItem(app, t)
+	defer DeleteItem(item, t)
+
+	trigger := CreateTrigger(item, host, t)
+	defer DeleteTrigger(trigger, t)
+
+	triggers, err := api.TriggersGet(Params{"triggerids": trigger.TriggerId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	triggers[0].ManualClose = TriggerManualCloseYes
+	triggers[0].Comments = "updated"
+	err = api.TriggersUpdate(triggers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	masters := Triggers{{Description: "Master for " + item.Key, Expression: "{" + host.Host + ":" + item.Key + ".last()}>100"}}
+	err = api.TriggersCreate(masters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DeleteTrigger(&masters[0], t)
+
+	err = api.TriggersAddDependencies(trigger.TriggerId, []string{masters[0].TriggerId})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	triggers, err = api.TriggersGetWithDetails([]string{trigger.TriggerId}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(triggers) != 1 {
+		t.Fatalf("Bad triggers: %#v", triggers)
+	}
+	trigger2 := triggers[0]
+	if trigger2.Comments != "updated" || len(trigger2.Functions) != 1 || len(trigger2.Hosts) != 1 || len(trigger2.Items) != 1 {
+		t.Errorf("Bad trigger: %#v", trigger2)
+	}
+	if len(trigger2.Dependencies) != 1 || trigger2.Dependencies[0].TriggerId != masters[0].TriggerId {
+		t.Errorf("Bad dependencies: %#v", trigger2.Dependencies)
+	}
+	if trigger2.Expression != trigger.Expression {
+		t.Errorf("Expression is not expanded: %s", trigger2.Expression)
+	}
+
+	err = api.TriggersDeleteDependencies([]string{trigger.TriggerId})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTriggersGetWithDetailsKeepsMacros(t *testing.T) {
+	var updated interface{}
+	api := stubAPI(func(method string, params interface{}) interface{} {
+		switch method {
+		case "trigger.get":
+			p := params.(map[string]interface{})
+			if p["expandExpression"] != true || p["expandDescription"] != nil || p["expandComment"] != nil {
+				t.Errorf("Bad params %v", p)
+			}
+			return []interface{}{map[string]interface{}{"triggerid": "30", "description": "High CPU on {HOST.NAME}",
+				"comments": "See {$RUNBOOK}", "expression": "{web:system.cpu.load.last()}>5"}}
+		case "trigger.update":
+			updated = params
+			return map[string]interface{}{"triggerids": []interface{}{"30"}}
+		}
+		t.Errorf("Unexpected call %s", method)
+		return nil
+	})
+	triggers, err := api.TriggersGetWithDetails([]string{"30"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = api.TriggersUpdate(triggers); err != nil {
+		t.Fatal(err)
+	}
+	trigger := updated.([]interface{})[0].(map[string]interface{})
+	if trigger["description"] != "High CPU on {HOST.NAME}" || trigger["comments"] != "See {$RUNBOOK}" {
+		t.Errorf("Macros are not kept: %v", trigger)
+	}
+}
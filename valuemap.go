@@ -0,0 +1,59 @@
+// value maps
+
+package zabbix
+
+import (
+	"github.com/wOvAN/reflector"
+)
+
+const (
+	// Value map Selectors
+	SelectMappings = "selectMappings"
+)
+
+// https://www.zabbix.com/documentation/4.0/manual/api/reference/valuemap/object
+type ValueMap struct {
+	ValueMapId string `json:"valuemapid,omitempty"`
+	Name       string `json:"name"`
+
+	// Returned with SelectMappings, used when creating value maps
+	Mappings ValueMappings `json:"mappings,omitempty"`
+}
+
+type ValueMaps []ValueMap
+
+type ValueMapping struct {
+	Value    string `json:"value"`
+	NewValue string `json:"newvalue"`
+}
+
+type ValueMappings []ValueMapping
+
+// Wrapper for valuemap.get: https://www.zabbix.com/documentation/4.0/manual/api/reference/valuemap/get
+func (api *API) ValueMapsGet(params Params) (res ValueMaps, err error) {
+	if _, present := params["output"]; !present {
+		params["output"] = "extend"
+	}
+	response, err := api.CallWithError("valuemap.get", params)
+	if err != nil {
+		return
+	}
+
+	reflector.MapsToStructs2(response.Result.([]interface{}), &res, reflector.Strconv, "json")
+	return
+}
+
+// Wrapper for valuemap.create: https://www.zabbix.com/documentation/4.0/manual/api/reference/valuemap/create
+func (api *API) ValueMapsCreate(maps ValueMaps) (err error) {
+	response, err := api.CallWithError("valuemap.create", maps)
+	if err != nil {
+		return
+	}
+
+	result := response.Result.(map[string]interface{})
+	valuemapids := result["valuemapids"].([]interface{})
+	for i, id := range valuemapids {
+		maps[i].ValueMapId = id.(string)
+	}
+	return
+}